@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
+	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/util/wait"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/cloud-provider/app"
@@ -15,7 +17,8 @@ import (
 	_ "k8s.io/component-base/metrics/prometheus/version"
 	"k8s.io/klog/v2"
 
-	_ "github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/cloud"
+	binarylanecloud "github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/cloud"
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/version"
 )
 
 func main() {
@@ -37,11 +40,34 @@ func main() {
 		wait.NeverStop,
 	)
 	command.Use = "binarylane-cloud-controller-manager"
+	addVersionCommand(command)
 
 	code := cli.Run(command)
 	os.Exit(code)
 }
 
+// addVersionCommand adds a version subcommand and makes --version report the
+// cloud controller manager build rather than the Kubernetes library version.
+func addVersionCommand(command *cobra.Command) {
+	command.AddCommand(&cobra.Command{
+		Use:   "version",
+		Short: "Print version information and quit",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprintln(cmd.OutOrStdout(), version.Get())
+		},
+	})
+
+	run := command.RunE
+	command.RunE = func(cmd *cobra.Command, args []string) error {
+		if flag := cmd.Flags().Lookup("version"); flag != nil && flag.Value.String() != "false" {
+			fmt.Fprintln(cmd.OutOrStdout(), version.Get())
+			return nil
+		}
+		return run(cmd, args)
+	}
+}
+
 func cloudInitializer(config *config.CompletedConfig) cloudprovider.Interface {
 	cloudConfig := config.ComponentConfig.KubeCloudShared.CloudProvider
 	cloud, err := cloudprovider.InitCloudProvider(cloudConfig.Name, cloudConfig.CloudConfigFile)
@@ -52,14 +78,22 @@ func cloudInitializer(config *config.CompletedConfig) cloudprovider.Interface {
 		klog.Fatalf("Cloud provider is nil")
 	}
 
-	// TODO: Uncomment after cluster IDs are implemented
-	// if !cloud.HasClusterID() {
-	// 	if config.ComponentConfig.KubeCloudShared.AllowUntaggedCloud {
-	// 		klog.Warning("detected a cluster without a ClusterID.  A ClusterID will be required in the future.  Please tag your cluster to avoid any future issues")
-	// 	} else {
-	// 		klog.Fatalf("no ClusterID found.  A ClusterID is required for the cloud provider to function properly.  This check can be bypassed by setting the allow-untagged-cloud option")
-	// 	}
-	// }
+	if blCloud, ok := cloud.(*binarylanecloud.Cloud); ok {
+		if err := blCloud.SetClusterCIDR(config.ComponentConfig.KubeCloudShared.ClusterCIDR); err != nil {
+			klog.Fatalf("Cloud provider could not be configured: %v", err)
+		}
+		if err := blCloud.SetClusterID(config.ComponentConfig.KubeCloudShared.ClusterName); err != nil {
+			klog.Fatalf("Cloud provider could not be configured: %v", err)
+		}
+	}
+
+	if !cloud.HasClusterID() {
+		if config.ComponentConfig.KubeCloudShared.AllowUntaggedCloud {
+			klog.Warning("detected a cluster without a ClusterID.  A ClusterID will be required in the future.  Please tag your cluster to avoid any future issues")
+		} else {
+			klog.Fatalf("no ClusterID found.  A ClusterID is required for the cloud provider to function properly.  This check can be bypassed by setting the allow-untagged-cloud option")
+		}
+	}
 
 	// TODO: There's a lot of potentially valuable configuration in config.ComponentConfig.KubeCloudShared..., consider passing it to the cloud provider here
 
@@ -0,0 +1,154 @@
+package binarylane
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultActionInterval    = time.Second
+	defaultActionMaxInterval = 15 * time.Second
+)
+
+var ErrActionNotFound = fmt.Errorf("action %w", ErrNotFound)
+
+// ActionError is returned when an action finishes with an errored status.
+type ActionError struct {
+	Action Action
+}
+
+func (e *ActionError) Error() string {
+	if e.Action.ResultData != nil && *e.Action.ResultData != "" {
+		return fmt.Sprintf("action %d (%s) errored: %s", e.Action.Id, e.Action.Type, *e.Action.ResultData)
+	}
+	return fmt.Sprintf("action %d (%s) errored", e.Action.Id, e.Action.Type)
+}
+
+// UserInteractionRequiredError is returned when an action is waiting on a
+// response from the user, which has to be given in the BinaryLane panel.
+type UserInteractionRequiredError struct {
+	ActionID        int64
+	InteractionType UserInteractionType
+}
+
+func (e *UserInteractionRequiredError) Error() string {
+	return fmt.Sprintf("action %d is waiting on user interaction %q", e.ActionID, e.InteractionType)
+}
+
+// BlockingInvoiceError is returned when an action is blocked until an invoice
+// is paid.
+type BlockingInvoiceError struct {
+	ActionID  int64
+	InvoiceID int64
+}
+
+func (e *BlockingInvoiceError) Error() string {
+	return fmt.Sprintf("action %d is blocked by unpaid invoice %d", e.ActionID, e.InvoiceID)
+}
+
+// WaitOptions control how WaitForAction polls an action.
+type WaitOptions struct {
+	// Interval is the delay between the first polls, doubled after each poll
+	// up to MaxInterval. Defaults to 1s, with a MaxInterval of 15s.
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// Timeout bounds the wait in addition to the context, if set.
+	Timeout time.Duration
+	// OnProgress is called with the action after every poll that finds it
+	// still in progress.
+	OnProgress func(action *Action)
+}
+
+func (c *BinaryLaneClient) GetAction(ctx context.Context, actionID int64) (*Action, error) {
+	resp, err := c.GetActionsActionId(ctx, actionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get action: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("%w: %w", ErrActionNotFound, newAPIError(resp))
+	}
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var actionResp ActionResponse
+	if err := json.Unmarshal(body, &actionResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &actionResp.Action, nil
+}
+
+// WaitForAction polls an action until it completes, and returns its final
+// state. An action that errors, or that cannot progress without the user,
+// is returned along with an ActionError, UserInteractionRequiredError or
+// BlockingInvoiceError.
+func (c *BinaryLaneClient) WaitForAction(ctx context.Context, actionID int64, opts WaitOptions) (*Action, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultActionInterval
+	}
+	maxInterval := opts.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = max(defaultActionMaxInterval, interval)
+	}
+
+	for {
+		action, err := c.GetAction(ctx, actionID)
+		if err != nil {
+			return nil, err
+		}
+		if done, err := actionDone(action); done {
+			return action, err
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(action)
+		}
+
+		// Jitter spreads out polls of actions started together
+		timer := time.NewTimer(interval/2 + rand.N(interval/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return action, fmt.Errorf("action %d (%s) did not complete: %w", action.Id, action.Type, ctx.Err())
+		case <-timer.C:
+		}
+		interval = min(interval*2, maxInterval)
+	}
+}
+
+// actionDone reports whether polling an action can stop, and why if it did
+// not complete.
+func actionDone(action *Action) (bool, error) {
+	switch {
+	case action.Status == Completed:
+		return true, nil
+	case action.Status == Errored:
+		return true, &ActionError{Action: *action}
+	case action.UserInteractionRequired != nil:
+		return true, &UserInteractionRequiredError{ActionID: action.Id, InteractionType: action.UserInteractionRequired.InteractionType}
+	case action.BlockingInvoiceId != nil:
+		return true, &BlockingInvoiceError{ActionID: action.Id, InvoiceID: *action.BlockingInvoiceId}
+	}
+	return false, nil
+}
@@ -0,0 +1,124 @@
+package binarylane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		final   string
+		check   func(t *testing.T, err error)
+		wantErr bool
+	}{
+		{
+			name:  "completed",
+			final: `"status": "completed", "completed_at": "2025-01-01T00:00:00Z"`,
+		},
+		{
+			name:  "errored",
+			final: `"status": "errored", "result_data": "no capacity"`,
+			check: func(t *testing.T, err error) {
+				var actionErr *ActionError
+				if !errors.As(err, &actionErr) || actionErr.Action.Id != 5 {
+					t.Errorf("error = %v, want ActionError for action 5", err)
+				}
+			},
+		},
+		{
+			name:  "user interaction required",
+			final: `"status": "in-progress", "user_interaction_required": {"interaction_type": "allow-unclean-power-off"}`,
+			check: func(t *testing.T, err error) {
+				var interactionErr *UserInteractionRequiredError
+				if !errors.As(err, &interactionErr) || interactionErr.InteractionType != "allow-unclean-power-off" {
+					t.Errorf("error = %v, want UserInteractionRequiredError", err)
+				}
+			},
+		},
+		{
+			name:  "blocked by invoice",
+			final: `"status": "in-progress", "blocking_invoice_id": 42`,
+			check: func(t *testing.T, err error) {
+				var invoiceErr *BlockingInvoiceError
+				if !errors.As(err, &invoiceErr) || invoiceErr.InvoiceID != 42 {
+					t.Errorf("error = %v, want BlockingInvoiceError for invoice 42", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var polls atomic.Int32
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/actions/5" {
+					t.Errorf("path = %s, want /actions/5", r.URL.Path)
+				}
+				status := `"status": "in-progress", "progress": {"percent_complete": 50}`
+				if polls.Add(1) >= 3 {
+					status = tt.final
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"action": {"id": 5, "type": "power_off", %s}}`, status)
+			})
+
+			var progress int
+			action, err := client.WaitForAction(context.Background(), 5, WaitOptions{
+				Interval:   time.Millisecond,
+				OnProgress: func(*Action) { progress++ },
+			})
+
+			if polls.Load() != 3 {
+				t.Errorf("polled %d times, want 3", polls.Load())
+			}
+			if progress != 2 {
+				t.Errorf("OnProgress called %d times, want 2", progress)
+			}
+			if tt.check == nil {
+				if err != nil {
+					t.Fatalf("WaitForAction() error = %v", err)
+				}
+				if action.Status != Completed {
+					t.Errorf("Status = %s, want completed", action.Status)
+				}
+				return
+			}
+			if action == nil {
+				t.Errorf("WaitForAction() returned no action with error %v", err)
+			}
+			tt.check(t, err)
+		})
+	}
+}
+
+func TestWaitForActionTimeout(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"action": {"id": 5, "status": "in-progress"}}`))
+	})
+
+	_, err := client.WaitForAction(context.Background(), 5, WaitOptions{
+		Interval: time.Millisecond,
+		Timeout:  20 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForAction() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestWaitForActionNotFound(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := client.WaitForAction(context.Background(), 5, WaitOptions{})
+	if !errors.Is(err, ErrActionNotFound) {
+		t.Errorf("WaitForAction() error = %v, want ErrActionNotFound", err)
+	}
+}
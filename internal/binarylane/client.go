@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"time"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/version"
 )
 
 const (
@@ -15,19 +16,26 @@ type BinaryLaneClient struct {
 	*Client
 }
 
-func NewBinaryLaneClient(token string) (*BinaryLaneClient, error) {
+// NewBinaryLaneClient creates a client for the BinaryLane API. Additional
+// options, such as WithBaseURL or WithRateLimit, are applied after the
+// defaults.
+func NewBinaryLaneClient(token string, opts ...ClientOption) (*BinaryLaneClient, error) {
+	// Timeouts are applied per attempt by the transport, so that retries are
+	// not cut short by an overall client timeout.
 	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
+		Transport: newTransport(http.DefaultTransport),
 	}
 
 	client, err := NewClient(
 		defaultBaseURL,
-		WithHTTPClient(httpClient),
-		WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
-			req.Header.Set("Authorization", "Bearer "+token)
-			req.Header.Set("User-Agent", "binarylane-cloud-controller-manager/v0") // TODO: set version dynamically
-			return nil
-		}),
+		append([]ClientOption{
+			WithHTTPClient(httpClient),
+			WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+				req.Header.Set("Authorization", "Bearer "+token)
+				req.Header.Set("User-Agent", version.UserAgent())
+				return nil
+			}),
+		}, opts...)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
@@ -27,3 +27,13 @@ func TestNewBinaryLaneClientEmpty(t *testing.T) {
 		t.Fatal("expected client to be created")
 	}
 }
+
+func TestNewBinaryLaneClientWithBaseURL(t *testing.T) {
+	client, err := NewBinaryLaneClient("test-token", WithBaseURL("https://api.example.com/v2"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.Server != "https://api.example.com/v2/" {
+		t.Fatalf("Server = %s, want https://api.example.com/v2/", client.Server)
+	}
+}
@@ -0,0 +1,108 @@
+package binarylane
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Sentinel errors matched by APIError through errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrConflict     = errors.New("conflict")
+)
+
+// APIError is a non-successful response from the BinaryLane API, decoded from
+// a ProblemDetails or ValidationProblemDetails body when one is present.
+type APIError struct {
+	StatusCode int
+	Title      string
+	Detail     string
+	// FieldErrors maps request fields to validation messages.
+	FieldErrors map[string][]string
+	// Body is the raw response body when it is not a problem details object.
+	Body string
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "API error (status %d)", e.StatusCode)
+
+	switch {
+	case e.Title != "" && e.Detail != "":
+		fmt.Fprintf(&b, ": %s: %s", e.Title, e.Detail)
+	case e.Title != "":
+		fmt.Fprintf(&b, ": %s", e.Title)
+	case e.Detail != "":
+		fmt.Fprintf(&b, ": %s", e.Detail)
+	case e.Body != "":
+		fmt.Fprintf(&b, ": %s", e.Body)
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(e.FieldErrors)) {
+		fmt.Fprintf(&b, "; %s: %s", field, strings.Join(e.FieldErrors[field], ", "))
+	}
+
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		b.WriteString(" (check that the BinaryLane API token is valid)")
+	case http.StatusForbidden:
+		b.WriteString(" (check that the BinaryLane API token has the required permissions)")
+	}
+
+	return b.String()
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+// newAPIError reads the response body and builds an APIError from it. The
+// caller remains responsible for closing the body.
+func newAPIError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("API error (status %d), failed to read response: %w", resp.StatusCode, err)
+	}
+
+	// ValidationProblemDetails is a superset of ProblemDetails
+	var problem ValidationProblemDetails
+	if err := json.Unmarshal(body, &problem); err != nil || (problem.Title == nil && problem.Detail == nil && problem.Errors == nil) {
+		apiErr.Body = strings.TrimSpace(string(body))
+		return apiErr
+	}
+
+	if problem.Title != nil {
+		apiErr.Title = *problem.Title
+	}
+	if problem.Detail != nil {
+		apiErr.Detail = *problem.Detail
+	}
+	if problem.Errors != nil {
+		apiErr.FieldErrors = *problem.Errors
+	}
+
+	return apiErr
+}
@@ -0,0 +1,91 @@
+package binarylane
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIErrorFromProblemDetails(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"title": "Unauthorized", "detail": "The access token is invalid.", "status": 401}`))
+	})
+
+	_, err := client.GetServer(context.Background(), 1)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("GetServer() error = %v, want ErrUnauthorized", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("GetServer() error = %v, should not be ErrNotFound", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetServer() error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Title != "Unauthorized" || apiErr.Detail != "The access token is invalid." {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+}
+
+func TestAPIErrorFromValidationProblemDetails(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"title": "One or more validation errors occurred.", "status": 400, "errors": {"Name": ["The Name field is required."]}}`))
+	})
+
+	_, err := client.CreateLoadBalancer(context.Background(), CreateLoadBalancerRequest{})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreateLoadBalancer() error = %v, want *APIError", err)
+	}
+	if got := apiErr.FieldErrors["Name"]; len(got) != 1 || got[0] != "The Name field is required." {
+		t.Errorf("FieldErrors = %v", apiErr.FieldErrors)
+	}
+	if !strings.Contains(err.Error(), "Name: The Name field is required.") {
+		t.Errorf("Error() = %q, want it to include field errors", err.Error())
+	}
+}
+
+func TestAPIErrorNotFound(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := client.GetVpc(context.Background(), 1)
+	if !errors.Is(err, ErrVpcNotFound) {
+		t.Errorf("GetVpc() error = %v, want ErrVpcNotFound", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVpc() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		status int
+		target error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusConflict, ErrConflict},
+	}
+
+	for _, tt := range tests {
+		err := &APIError{StatusCode: tt.status}
+		if !errors.Is(err, tt.target) {
+			t.Errorf("errors.Is(status %d, %v) = false, want true", tt.status, tt.target)
+		}
+		if errors.Is(&APIError{StatusCode: http.StatusInternalServerError}, tt.target) {
+			t.Errorf("errors.Is(status 500, %v) = true, want false", tt.target)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package binarylane
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+var ErrLoadBalancerNotFound = fmt.Errorf("load balancer %w", ErrNotFound)
+
+func (c *BinaryLaneClient) ListLoadBalancers(ctx context.Context) ([]LoadBalancer, error) {
+	return collect(c.AllLoadBalancers(ctx, PageOptions{}))
+}
+
+func (c *BinaryLaneClient) GetLoadBalancer(ctx context.Context, loadBalancerID int64) (*LoadBalancer, error) {
+	resp, err := c.GetLoadBalancersLoadBalancerId(ctx, loadBalancerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get load balancer: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("%w: %w", ErrLoadBalancerNotFound, newAPIError(resp))
+	}
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var lbResp LoadBalancerResponse
+	if err := json.Unmarshal(body, &lbResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &lbResp.LoadBalancer, nil
+}
+
+func (c *BinaryLaneClient) GetLoadBalancerByName(ctx context.Context, name string) (*LoadBalancer, error) {
+	lbs, err := c.ListLoadBalancers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range lbs {
+		if lbs[i].Name == name {
+			return &lbs[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrLoadBalancerNotFound, name)
+}
+
+func (c *BinaryLaneClient) CreateLoadBalancer(ctx context.Context, req CreateLoadBalancerRequest) (*LoadBalancer, error) {
+	resp, err := c.PostLoadBalancers(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create load balancer: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var lbResp CreateLoadBalancerResponse
+	if err := json.Unmarshal(body, &lbResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &lbResp.LoadBalancer, nil
+}
+
+func (c *BinaryLaneClient) UpdateLoadBalancer(ctx context.Context, loadBalancerID int64, req UpdateLoadBalancerRequest) (*LoadBalancer, error) {
+	resp, err := c.PutLoadBalancersLoadBalancerId(ctx, loadBalancerID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update load balancer: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("%w: %w", ErrLoadBalancerNotFound, newAPIError(resp))
+	}
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var lbResp UpdateLoadBalancerResponse
+	if err := json.Unmarshal(body, &lbResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &lbResp.LoadBalancer, nil
+}
+
+func (c *BinaryLaneClient) AddLoadBalancerServers(ctx context.Context, loadBalancerID int64, serverIDs []int64) error {
+	resp, err := c.PostLoadBalancersLoadBalancerIdServers(ctx, loadBalancerID, ServerIdsRequest{ServerIds: serverIDs})
+	if err != nil {
+		return fmt.Errorf("failed to add load balancer servers: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode == 404 {
+		return fmt.Errorf("%w: %w", ErrLoadBalancerNotFound, newAPIError(resp))
+	}
+	if resp.StatusCode != 204 {
+		return newAPIError(resp)
+	}
+
+	return nil
+}
+
+func (c *BinaryLaneClient) RemoveLoadBalancerServers(ctx context.Context, loadBalancerID int64, serverIDs []int64) error {
+	resp, err := c.DeleteLoadBalancersLoadBalancerIdServers(ctx, loadBalancerID, ServerIdsRequest{ServerIds: serverIDs})
+	if err != nil {
+		return fmt.Errorf("failed to remove load balancer servers: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode == 404 {
+		return fmt.Errorf("%w: %w", ErrLoadBalancerNotFound, newAPIError(resp))
+	}
+	if resp.StatusCode != 204 {
+		return newAPIError(resp)
+	}
+
+	return nil
+}
+
+func (c *BinaryLaneClient) DeleteLoadBalancer(ctx context.Context, loadBalancerID int64) error {
+	resp, err := c.DeleteLoadBalancersLoadBalancerId(ctx, loadBalancerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete load balancer: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode == 404 {
+		return fmt.Errorf("%w: %w", ErrLoadBalancerNotFound, newAPIError(resp))
+	}
+	if resp.StatusCode != 204 {
+		return newAPIError(resp)
+	}
+
+	return nil
+}
+
+// GetLoadBalancersAvailability returns the load balancer options that can be
+// created, with the regions they are available in and their prices.
+func (c *BinaryLaneClient) GetLoadBalancersAvailability(ctx context.Context) ([]LoadBalancerAvailabilityOption, error) {
+	resp, err := c.Client.GetLoadBalancersAvailability(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get load balancer availability: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var availabilityResp LoadBalancerAvailabilityResponse
+	if err := json.Unmarshal(body, &availabilityResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return availabilityResp.LoadBalancerAvailabilityOptions, nil
+}
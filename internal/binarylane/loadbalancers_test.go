@@ -0,0 +1,46 @@
+package binarylane
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestGetLoadBalancersAvailability(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/load_balancers/availability" {
+			t.Errorf("path = %s, want /load_balancers/availability", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"load_balancer_availability_options": [
+			{"anycast": false, "regions": ["syd", "mel"], "price_hourly": 0.015, "price_monthly": 10},
+			{"anycast": true, "regions": null, "price_hourly": 0.03, "price_monthly": 20}
+		]}`))
+	})
+
+	options, err := client.GetLoadBalancersAvailability(context.Background())
+	if err != nil {
+		t.Fatalf("GetLoadBalancersAvailability() error = %v", err)
+	}
+	if len(options) != 2 {
+		t.Fatalf("got %d options, want 2", len(options))
+	}
+	if options[0].Anycast || options[0].Regions == nil || len(*options[0].Regions) != 2 || options[0].PriceMonthly != 10 {
+		t.Errorf("options[0] = %+v, want regional option in syd and mel", options[0])
+	}
+	if !options[1].Anycast || options[1].Regions != nil || options[1].PriceHourly != 0.03 {
+		t.Errorf("options[1] = %+v, want anycast option", options[1])
+	}
+}
+
+func TestGetLoadBalancersAvailabilityError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	_, err := client.GetLoadBalancersAvailability(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("GetLoadBalancersAvailability() error = %v, want ErrUnauthorized", err)
+	}
+}
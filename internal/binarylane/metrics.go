@@ -0,0 +1,95 @@
+package binarylane
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsNamespace = "binarylane_ccm"
+
+var (
+	apiRequestsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "api",
+			Name:           "requests_total",
+			Help:           "Number of BinaryLane API requests by operation and status code.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation", "code"},
+	)
+
+	apiRequestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "api",
+			Name:           "request_duration_seconds",
+			Help:           "Latency of BinaryLane API requests by operation and status code.",
+			Buckets:        metrics.ExponentialBuckets(0.05, 2, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation", "code"},
+	)
+
+	apiRetriesTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "api",
+			Name:           "retries_total",
+			Help:           "Number of BinaryLane API requests retried after a transient failure.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation"},
+	)
+
+	apiRateLimitWait = metrics.NewHistogram(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "api",
+			Name:           "rate_limit_wait_duration_seconds",
+			Help:           "Time spent waiting on the client-side rate limiter before a BinaryLane API request.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 4, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	registerMetricsOnce sync.Once
+)
+
+// RegisterMetrics registers the BinaryLane API metrics with the legacy
+// registry, which is served on the cloud controller manager's metrics
+// endpoint.
+func RegisterMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(apiRequestsTotal)
+		legacyregistry.MustRegister(apiRequestDuration)
+		legacyregistry.MustRegister(apiRetriesTotal)
+		legacyregistry.MustRegister(apiRateLimitWait)
+	})
+}
+
+// operationName returns a low-cardinality name for a request, replacing
+// resource IDs in the path, e.g. "GET /v2/servers/{id}".
+func operationName(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, segment := range segments {
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+	return req.Method + " /" + strings.Join(segments, "/")
+}
+
+// statusCode returns the metric label for a response, or "error" when the
+// request failed without one.
+func statusCode(resp *http.Response, err error) string {
+	if err != nil || resp == nil {
+		return "error"
+	}
+	return strconv.Itoa(resp.StatusCode)
+}
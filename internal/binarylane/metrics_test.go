@@ -0,0 +1,74 @@
+package binarylane
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestOperationName(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/v2/servers", "GET /v2/servers"},
+		{http.MethodGet, "/v2/servers/123", "GET /v2/servers/{id}"},
+		{http.MethodPut, "/v2/vpcs/42", "PUT /v2/vpcs/{id}"},
+		{http.MethodPost, "/v2/load_balancers/7/servers", "POST /v2/load_balancers/{id}/servers"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := operationName(req); got != tt.want {
+			t.Errorf("operationName(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTransportRecordsMetrics(t *testing.T) {
+	RegisterMetrics()
+
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"server": {"id": 1, "name": "server-1"}}`))
+	})
+
+	const operation = "GET /servers/{id}"
+	okBefore := counterValue(t, apiRequestsTotal.WithLabelValues(operation, "200"))
+	unavailableBefore := counterValue(t, apiRequestsTotal.WithLabelValues(operation, "503"))
+	retriesBefore := counterValue(t, apiRetriesTotal.WithLabelValues(operation))
+
+	if _, err := client.GetServer(context.Background(), 1); err != nil {
+		t.Fatalf("GetServer() error = %v", err)
+	}
+
+	if got := counterValue(t, apiRequestsTotal.WithLabelValues(operation, "200")) - okBefore; got != 1 {
+		t.Errorf("requests with code 200 = %v, want 1", got)
+	}
+	if got := counterValue(t, apiRequestsTotal.WithLabelValues(operation, "503")) - unavailableBefore; got != 1 {
+		t.Errorf("requests with code 503 = %v, want 1", got)
+	}
+	if got := counterValue(t, apiRetriesTotal.WithLabelValues(operation)) - retriesBefore; got != 1 {
+		t.Errorf("retries = %v, want 1", got)
+	}
+}
+
+func counterValue(t *testing.T, m metrics.CounterMetric) float64 {
+	t.Helper()
+	value, err := testutil.GetCounterMetricValue(m)
+	if err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+	return value
+}
@@ -0,0 +1,185 @@
+package binarylane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+const defaultMaxPages = 1000
+
+// ErrTooManyPages is returned when a listing has more pages than
+// PageOptions.MaxPages allows.
+var ErrTooManyPages = errors.New("too many pages")
+
+// PageOptions control how a list endpoint is paged through.
+type PageOptions struct {
+	// PerPage is the number of items requested per page. The API default is
+	// used when zero.
+	PerPage int32
+	// MaxPages guards against endless paging, e.g. when items are created
+	// faster than they are listed. Defaults to 1000.
+	MaxPages int
+}
+
+// fetchPage requests one page of a list endpoint, returning its items and the
+// links to further pages.
+type fetchPage[T any] func(ctx context.Context, page int32, perPage *int32) ([]T, *Links, error)
+
+// paginate iterates over every item of a list endpoint, requesting pages as
+// they are needed. Iteration stops at the first error, which is yielded with
+// the zero value.
+func paginate[T any](ctx context.Context, opts PageOptions, fetch fetchPage[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		maxPages := opts.MaxPages
+		if maxPages <= 0 {
+			maxPages = defaultMaxPages
+		}
+		var perPage *int32
+		if opts.PerPage > 0 {
+			perPage = &opts.PerPage
+		}
+
+		for page := int32(1); ; page++ {
+			if int(page) > maxPages {
+				yield(zero, fmt.Errorf("%w: stopped after %d pages", ErrTooManyPages, maxPages))
+				return
+			}
+
+			items, links, err := fetch(ctx, page, perPage)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if links == nil || links.Pages.Next == nil {
+				return
+			}
+		}
+	}
+}
+
+// collect gathers every item of a listing into a slice.
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// decodePage decodes a list response into R, and closes its body.
+func decodePage[R any](resp *http.Response) (*R, error) {
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var page R
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &page, nil
+}
+
+// AllServers iterates over every server in the account.
+func (c *BinaryLaneClient) AllServers(ctx context.Context, opts PageOptions) iter.Seq2[Server, error] {
+	return paginate(ctx, opts, func(ctx context.Context, page int32, perPage *int32) ([]Server, *Links, error) {
+		resp, err := c.GetServers(ctx, &GetServersParams{Page: &page, PerPage: perPage})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list servers: %w", err)
+		}
+		serversResp, err := decodePage[ServersResponse](resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		return serversResp.Servers, serversResp.Links, nil
+	})
+}
+
+// AllVpcs iterates over every VPC in the account.
+func (c *BinaryLaneClient) AllVpcs(ctx context.Context, opts PageOptions) iter.Seq2[Vpc, error] {
+	return paginate(ctx, opts, func(ctx context.Context, page int32, perPage *int32) ([]Vpc, *Links, error) {
+		resp, err := c.GetVpcs(ctx, &GetVpcsParams{Page: &page, PerPage: perPage})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list VPCs: %w", err)
+		}
+		vpcsResp, err := decodePage[VpcsResponse](resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		return vpcsResp.Vpcs, vpcsResp.Links, nil
+	})
+}
+
+// AllVpcMembers iterates over the members of a VPC, optionally filtered by
+// params.ResourceType. The paging fields of params are ignored.
+func (c *BinaryLaneClient) AllVpcMembers(ctx context.Context, vpcID int64, params GetVpcsVpcIdMembersParams, opts PageOptions) iter.Seq2[VpcMember, error] {
+	return paginate(ctx, opts, func(ctx context.Context, page int32, perPage *int32) ([]VpcMember, *Links, error) {
+		params.Page, params.PerPage = &page, perPage
+		resp, err := c.GetVpcsVpcIdMembers(ctx, vpcID, &params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list VPC members: %w", err)
+		}
+		if resp.StatusCode == 404 {
+			defer func() { _ = resp.Body.Close() }()
+			return nil, nil, fmt.Errorf("%w: %w", ErrVpcNotFound, newAPIError(resp))
+		}
+		membersResp, err := decodePage[VpcMembersResponse](resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		return membersResp.Members, membersResp.Links, nil
+	})
+}
+
+// AllLoadBalancers iterates over every load balancer in the account.
+func (c *BinaryLaneClient) AllLoadBalancers(ctx context.Context, opts PageOptions) iter.Seq2[LoadBalancer, error] {
+	return paginate(ctx, opts, func(ctx context.Context, page int32, perPage *int32) ([]LoadBalancer, *Links, error) {
+		resp, err := c.GetLoadBalancers(ctx, &GetLoadBalancersParams{Page: &page, PerPage: perPage})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list load balancers: %w", err)
+		}
+		lbsResp, err := decodePage[LoadBalancersResponse](resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		return lbsResp.LoadBalancers, lbsResp.Links, nil
+	})
+}
+
+// AllImages iterates over the images available to the account, filtered by
+// params.Type and params.Private. The paging fields of params are ignored.
+func (c *BinaryLaneClient) AllImages(ctx context.Context, params GetImagesParams, opts PageOptions) iter.Seq2[Image, error] {
+	return paginate(ctx, opts, func(ctx context.Context, page int32, perPage *int32) ([]Image, *Links, error) {
+		params.Page, params.PerPage = &page, perPage
+		resp, err := c.GetImages(ctx, &params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list images: %w", err)
+		}
+		imagesResp, err := decodePage[ImagesResponse](resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		return imagesResp.Images, imagesResp.Links, nil
+	})
+}
@@ -0,0 +1,133 @@
+package binarylane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// pagedHandler serves VPC members split into pages of the requested size.
+func pagedHandler(t *testing.T, total int, requests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		query := r.URL.Query()
+		page, _ := strconv.Atoi(query.Get("page"))
+		perPage, err := strconv.Atoi(query.Get("per_page"))
+		if err != nil {
+			perPage = 20
+		}
+		if got := query.Get("resource_type"); got != "server" {
+			t.Errorf("resource_type = %q, want server", got)
+		}
+
+		var members string
+		for i := (page-1)*perPage + 1; i <= min(page*perPage, total); i++ {
+			if members != "" {
+				members += ","
+			}
+			members += fmt.Sprintf(`{"name": "node-%d", "resource_id": "%d", "resource_type": "server"}`, i, i)
+		}
+		next := "null"
+		if page*perPage < total {
+			next = fmt.Sprintf(`"/vpcs/1/members?page=%d"`, page+1)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"members": [%s], "links": {"pages": {"next": %s}}, "meta": {"total": %d}}`, members, next, total)
+	}
+}
+
+func TestAllVpcMembers(t *testing.T) {
+	serverType := ResourceType("server")
+	params := GetVpcsVpcIdMembersParams{ResourceType: &serverType}
+
+	tests := []struct {
+		name         string
+		total        int
+		opts         PageOptions
+		wantMembers  int
+		wantRequests int32
+		wantErr      error
+	}{
+		{
+			name:         "default page size",
+			total:        45,
+			wantMembers:  45,
+			wantRequests: 3,
+		},
+		{
+			name:         "per page sizing",
+			total:        45,
+			opts:         PageOptions{PerPage: 50},
+			wantMembers:  45,
+			wantRequests: 1,
+		},
+		{
+			name:         "max pages",
+			total:        45,
+			opts:         PageOptions{PerPage: 10, MaxPages: 2},
+			wantRequests: 2,
+			wantErr:      ErrTooManyPages,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			client := newTestClient(t, pagedHandler(t, tt.total, &requests))
+
+			members, err := collect(client.AllVpcMembers(context.Background(), 1, params, tt.opts))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AllVpcMembers() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(members) != tt.wantMembers {
+				t.Errorf("got %d members, want %d", len(members), tt.wantMembers)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestPaginateStopsWhenIterationEnds(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, pagedHandler(t, 100, &requests))
+
+	serverType := ResourceType("server")
+	var names []string
+	for member, err := range client.AllVpcMembers(context.Background(), 1, GetVpcsVpcIdMembersParams{ResourceType: &serverType}, PageOptions{PerPage: 10}) {
+		if err != nil {
+			t.Fatalf("AllVpcMembers() error = %v", err)
+		}
+		names = append(names, member.Name)
+		if len(names) == 15 {
+			break
+		}
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("made %d requests, want 2", got)
+	}
+}
+
+func TestPaginateYieldsErrors(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	var calls int
+	for _, err := range client.AllVpcMembers(context.Background(), 1, GetVpcsVpcIdMembersParams{}, PageOptions{}) {
+		calls++
+		if !errors.Is(err, ErrVpcNotFound) {
+			t.Errorf("error = %v, want ErrVpcNotFound", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("iteration yielded %d times, want 1", calls)
+	}
+}
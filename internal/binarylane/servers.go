@@ -6,49 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
-var ErrServerNotFound = errors.New("server not found")
+var (
+	ErrServerNotFound  = fmt.Errorf("server %w", ErrNotFound)
+	ErrMultipleServers = errors.New("multiple servers match")
+)
 
 func (c *BinaryLaneClient) ListServers(ctx context.Context) ([]Server, error) {
-	var allServers []Server
-	page := int32(1)
-
-	for {
-		resp, err := c.GetServers(ctx, &GetServersParams{Page: &page})
-		if err != nil {
-			return nil, fmt.Errorf("failed to list servers: %w", err)
-		}
-
-		if resp.StatusCode != 200 {
-			body, readErr := io.ReadAll(resp.Body)
-			_ = resp.Body.Close()
-			if readErr != nil {
-				return nil, fmt.Errorf("API error (status %d), failed to read response: %w", resp.StatusCode, readErr)
-			}
-			return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
-		}
-
-		var serversResp ServersResponse
-		if err := json.Unmarshal(body, &serversResp); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-		}
-
-		allServers = append(allServers, serversResp.Servers...)
-
-		if serversResp.Links == nil || serversResp.Links.Pages.Next == nil {
-			break
-		}
-		page++
-	}
-
-	return allServers, nil
+	return collect(c.AllServers(ctx, PageOptions{}))
 }
 
 func (c *BinaryLaneClient) GetServer(ctx context.Context, serverID int64) (*Server, error) {
@@ -63,14 +30,10 @@ func (c *BinaryLaneClient) GetServer(ctx context.Context, serverID int64) (*Serv
 	}()
 
 	if resp.StatusCode == 404 {
-		return nil, ErrServerNotFound
+		return nil, fmt.Errorf("%w: %w", ErrServerNotFound, newAPIError(resp))
 	}
 	if resp.StatusCode != 200 {
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			return nil, fmt.Errorf("API error (status %d), failed to read response: %w", resp.StatusCode, readErr)
-		}
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -101,11 +64,7 @@ func (c *BinaryLaneClient) GetServerByName(ctx context.Context, name string) (*S
 	}()
 
 	if resp.StatusCode != 200 {
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			return nil, fmt.Errorf("API error (status %d), failed to read response: %w", resp.StatusCode, readErr)
-		}
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -118,9 +77,60 @@ func (c *BinaryLaneClient) GetServerByName(ctx context.Context, name string) (*S
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	if len(serversResp.Servers) == 0 {
+	// The hostname filter is not guaranteed to be an exact match
+	var matches []Server
+	for _, server := range serversResp.Servers {
+		if strings.EqualFold(server.Name, name) {
+			matches = append(matches, server)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
 		return nil, fmt.Errorf("%w: %s", ErrServerNotFound, name)
+	case 1:
+		return &matches[0], nil
+	default:
+		descriptions := make([]string, len(matches))
+		for i, server := range matches {
+			descriptions[i] = fmt.Sprintf("%d (%s)", server.Id, server.Name)
+		}
+		return nil, fmt.Errorf("%w: hostname %s: %s", ErrMultipleServers, name, strings.Join(descriptions, ", "))
+	}
+}
+
+// ChangeSourceAndDestinationCheck enables or disables the server's source and
+// destination check, and returns the action that applies the change.
+func (c *BinaryLaneClient) ChangeSourceAndDestinationCheck(ctx context.Context, serverID int64, enabled bool) (*Action, error) {
+	resp, err := c.PostServersServerIdActionsChangeSourceAndDestinationCheck(ctx, serverID, ChangeSourceAndDestinationCheck{
+		Enabled: enabled,
+		Type:    ChangeSourceAndDestinationCheckTypeChangeSourceAndDestinationCheck,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to change source and destination check: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("%w: %w", ErrServerNotFound, newAPIError(resp))
+	}
+	if resp.StatusCode != 200 && resp.StatusCode != 202 {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var actionResp ActionResponse
+	if err := json.Unmarshal(body, &actionResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &serversResp.Servers[0], nil
+	return &actionResp.Action, nil
 }
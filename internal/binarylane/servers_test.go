@@ -3,8 +3,10 @@ package binarylane
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -119,3 +121,65 @@ func TestListServersNoPagination(t *testing.T) {
 		t.Errorf("ListServers() returned %d servers, want %d", len(servers), len(allServers))
 	}
 }
+
+func TestGetServerByName(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// The hostname filter may return partial matches
+		resp := ServersResponse{Servers: []Server{
+			{Id: 1, Name: "web-1"},
+			{Id: 2, Name: "web-10"},
+			{Id: 3, Name: "db-1"},
+			{Id: 4, Name: "DB-1"},
+		}}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	server, err := client.GetServerByName(context.Background(), "web-1")
+	if err != nil {
+		t.Fatalf("GetServerByName() error = %v", err)
+	}
+	if server.Id != 1 {
+		t.Errorf("GetServerByName() = %d, want 1", server.Id)
+	}
+
+	if _, err := client.GetServerByName(context.Background(), "web"); !errors.Is(err, ErrServerNotFound) {
+		t.Errorf("GetServerByName() error = %v, want ErrServerNotFound", err)
+	}
+
+	_, err = client.GetServerByName(context.Background(), "db-1")
+	if !errors.Is(err, ErrMultipleServers) {
+		t.Fatalf("GetServerByName() error = %v, want ErrMultipleServers", err)
+	}
+	if !strings.Contains(err.Error(), "3 (db-1), 4 (DB-1)") {
+		t.Errorf("GetServerByName() error = %v, want it to list the matches", err)
+	}
+}
+
+func TestChangeSourceAndDestinationCheck(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/servers/1/actions" {
+			t.Errorf("request = %s %s, want POST /servers/1/actions", r.Method, r.URL.Path)
+		}
+
+		var body ChangeSourceAndDestinationCheck
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		if body.Enabled || body.Type != ChangeSourceAndDestinationCheckTypeChangeSourceAndDestinationCheck {
+			t.Errorf("body = %+v, want disabled change_source_and_destination_check", body)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"action": {"id": 7, "status": "in-progress", "type": "change_source_and_destination_check"}}`))
+	})
+
+	action, err := client.ChangeSourceAndDestinationCheck(context.Background(), 1, false)
+	if err != nil {
+		t.Fatalf("ChangeSourceAndDestinationCheck() error = %v", err)
+	}
+	if action.Id != 7 || action.Status != InProgress {
+		t.Errorf("action = %+v, want action 7 in progress", action)
+	}
+}
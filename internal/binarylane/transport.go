@@ -0,0 +1,225 @@
+package binarylane
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultMaxRetries     = 4
+	defaultRateLimitQPS   = 5
+	defaultRateLimitBurst = 10
+	defaultRequestTimeout = 30 * time.Second
+	defaultBaseDelay      = 500 * time.Millisecond
+	defaultMaxDelay       = 30 * time.Second
+)
+
+// transport rate limits requests to the BinaryLane API and retries idempotent
+// requests that fail with a transient error. A single transport is shared by
+// every controller using the client, so the rate limit applies to the whole
+// cloud controller manager.
+type transport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+
+	maxRetries int
+	timeout    time.Duration
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+func newTransport(next http.RoundTripper) *transport {
+	return &transport{
+		next:       next,
+		limiter:    rate.NewLimiter(defaultRateLimitQPS, defaultRateLimitBurst),
+		maxRetries: defaultMaxRetries,
+		timeout:    defaultRequestTimeout,
+		baseDelay:  defaultBaseDelay,
+		maxDelay:   defaultMaxDelay,
+	}
+}
+
+// WithRateLimit sets the client-side rate limit for API requests. A qps of
+// zero or less disables rate limiting.
+func WithRateLimit(qps float64, burst int) ClientOption {
+	return func(c *Client) error {
+		t, err := clientTransport(c)
+		if err != nil {
+			return err
+		}
+		if qps <= 0 {
+			t.limiter = rate.NewLimiter(rate.Inf, 0)
+			return nil
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		t.limiter = rate.NewLimiter(rate.Limit(qps), burst)
+		return nil
+	}
+}
+
+// WithMaxRetries sets how many times a failed idempotent request is retried.
+func WithMaxRetries(maxRetries int) ClientOption {
+	return func(c *Client) error {
+		t, err := clientTransport(c)
+		if err != nil {
+			return err
+		}
+		if maxRetries < 0 {
+			return fmt.Errorf("max retries must not be negative")
+		}
+		t.maxRetries = maxRetries
+		return nil
+	}
+}
+
+func clientTransport(c *Client) (*transport, error) {
+	if httpClient, ok := c.Client.(*http.Client); ok {
+		if t, ok := httpClient.Transport.(*transport); ok {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("client is not using the BinaryLane transport")
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	operation := operationName(req)
+
+	for attempt := 0; ; attempt++ {
+		waitStart := time.Now()
+		if err := t.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limit wait: %w", err)
+		}
+		apiRateLimitWait.Observe(time.Since(waitStart).Seconds())
+
+		attemptReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, t.timeout)
+		start := time.Now()
+		resp, err := t.next.RoundTrip(attemptReq.WithContext(attemptCtx))
+
+		code := statusCode(resp, err)
+		apiRequestsTotal.WithLabelValues(operation, code).Inc()
+		apiRequestDuration.WithLabelValues(operation, code).Observe(time.Since(start).Seconds())
+
+		if attempt >= t.maxRetries || !isIdempotent(req.Method) || !shouldRetry(req, resp, err) || ctx.Err() != nil {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(retryAfter, t.maxDelay)
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		cancel()
+		apiRetriesTotal.WithLabelValues(operation).Inc()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns an exponential delay with full jitter.
+func (t *transport) backoff(attempt int) time.Duration {
+	delay := t.baseDelay << attempt
+	if delay <= 0 || delay > t.maxDelay {
+		delay = t.maxDelay
+	}
+	return rand.N(delay) + 1
+}
+
+// rewindRequest returns a request with a fresh body for retries.
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("cannot retry %s %s: request body is not rewindable", req.Method, req.URL)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether a failed attempt is transient. A 500 may come
+// from a request that was partially applied, so it is only retried for reads.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusInternalServerError:
+		return req.Method == http.MethodGet || req.Method == http.MethodHead
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header in either delay-seconds or
+// HTTP-date form.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// cancelOnClose releases the per-attempt context once the response body has
+// been consumed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,182 @@
+package binarylane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...ClientOption) *BinaryLaneClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewBinaryLaneClient("test-token", append([]ClientOption{WithBaseURL(server.URL)}, opts...)...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	tr, err := clientTransport(client.Client)
+	if err != nil {
+		t.Fatalf("failed to get transport: %v", err)
+	}
+	tr.baseDelay = time.Millisecond
+	tr.maxDelay = 10 * time.Millisecond
+
+	return client
+}
+
+func TestTransportRetriesRateLimited(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"server": {"id": 1, "name": "server-1"}}`))
+	})
+
+	server, err := client.GetServer(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetServer() error = %v", err)
+	}
+	if server.Id != 1 {
+		t.Errorf("server.Id = %d, want 1", server.Id)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestTransportRetriesServerErrors(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithMaxRetries(2))
+
+	_, err := client.GetServer(context.Background(), 1)
+	if err == nil {
+		t.Fatal("GetServer() expected error, got nil")
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestTransportInternalServerError(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}, WithMaxRetries(2))
+
+	if _, err := client.GetServer(context.Background(), 1); err == nil {
+		t.Fatal("GetServer() expected error, got nil")
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("GetServer() requests = %d, want 3", got)
+	}
+
+	requests.Store(0)
+	if _, err := client.UpdateVpc(context.Background(), 1, UpdateVpcRequest{Name: "vpc"}); err == nil {
+		t.Fatal("UpdateVpc() expected error, got nil")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("UpdateVpc() requests = %d, want 1", got)
+	}
+}
+
+func TestTransportDoesNotRetryNonIdempotent(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := client.CreateLoadBalancer(context.Background(), CreateLoadBalancerRequest{Name: "lb"})
+	if err == nil {
+		t.Fatal("CreateLoadBalancer() expected error, got nil")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestTransportRetriesWithBody(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body UpdateVpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name != "vpc" {
+			t.Errorf("request %d: unexpected body %+v (err: %v)", requests.Load(), body, err)
+		}
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"vpc": {"id": 1, "name": "vpc"}}`))
+	})
+
+	if _, err := client.UpdateVpc(context.Background(), 1, UpdateVpcRequest{Name: "vpc"}); err != nil {
+		t.Fatalf("UpdateVpc() error = %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestTransportContextCancelled(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	tr, _ := clientTransport(client.Client)
+	tr.maxDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetServer(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetServer() error = %v, want context deadline exceeded", err)
+	}
+}
+
+func TestTransportRateLimit(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"server": {"id": 1}}`))
+	}, WithRateLimit(20, 1))
+
+	start := time.Now()
+	for range 3 {
+		if _, err := client.GetServer(context.Background(), 1); err != nil {
+			t.Fatalf("GetServer() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests at 20 qps took %v, want at least 100ms", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("5"); !ok || d != 5*time.Second {
+		t.Errorf("parseRetryAfter(5) = %v, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("parseRetryAfter(soon) should fail")
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d <= 0 {
+		t.Errorf("parseRetryAfter(%s) = %v, %v", date, d, ok)
+	}
+}
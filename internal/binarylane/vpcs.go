@@ -3,12 +3,15 @@ package binarylane
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 )
 
-var ErrVpcNotFound = errors.New("VPC not found")
+var ErrVpcNotFound = fmt.Errorf("VPC %w", ErrNotFound)
+
+func (c *BinaryLaneClient) ListVpcs(ctx context.Context) ([]Vpc, error) {
+	return collect(c.AllVpcs(ctx, PageOptions{}))
+}
 
 func (c *BinaryLaneClient) GetVpc(ctx context.Context, vpcID int64) (*Vpc, error) {
 	resp, err := c.GetVpcsVpcId(ctx, vpcID)
@@ -22,14 +25,10 @@ func (c *BinaryLaneClient) GetVpc(ctx context.Context, vpcID int64) (*Vpc, error
 	}()
 
 	if resp.StatusCode == 404 {
-		return nil, ErrVpcNotFound
+		return nil, fmt.Errorf("%w: %w", ErrVpcNotFound, newAPIError(resp))
 	}
 	if resp.StatusCode != 200 {
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			return nil, fmt.Errorf("API error (status %d), failed to read response: %w", resp.StatusCode, readErr)
-		}
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -57,14 +56,44 @@ func (c *BinaryLaneClient) UpdateVpc(ctx context.Context, vpcID int64, req Updat
 	}()
 
 	if resp.StatusCode == 404 {
-		return nil, ErrVpcNotFound
+		return nil, fmt.Errorf("%w: %w", ErrVpcNotFound, newAPIError(resp))
 	}
 	if resp.StatusCode != 200 {
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			return nil, fmt.Errorf("API error (status %d), failed to read response: %w", resp.StatusCode, readErr)
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var vpcResp VpcResponse
+	if err := json.Unmarshal(body, &vpcResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &vpcResp.Vpc, nil
+}
+
+// PatchVpc updates only the fields of a VPC that are set in the request. Route
+// entries cannot be patched individually, so a non-nil RouteEntries replaces
+// the whole route table.
+func (c *BinaryLaneClient) PatchVpc(ctx context.Context, vpcID int64, req PatchVpcRequest) (*Vpc, error) {
+	resp, err := c.PatchVpcsVpcId(ctx, vpcID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch VPC: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}()
+
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("%w: %w", ErrVpcNotFound, newAPIError(resp))
+	}
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
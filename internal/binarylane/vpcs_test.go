@@ -0,0 +1,39 @@
+package binarylane
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestPatchVpcLeavesUnsetFieldsUnaltered(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/vpcs/1" {
+			t.Errorf("request = %s %s, want PATCH /vpcs/1", r.Method, r.URL.Path)
+		}
+
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		if string(body["name"]) != "null" {
+			t.Errorf("name = %s, want null", body["name"])
+		}
+		if string(body["route_entries"]) != `[{"description":null,"destination":"10.244.1.0/24","router":"10.0.0.1"}]` {
+			t.Errorf("route_entries = %s", body["route_entries"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"vpc": {"id": 1, "name": "vpc"}}`))
+	})
+
+	entries := []RouteEntryRequest{{Router: "10.0.0.1", Destination: "10.244.1.0/24"}}
+	vpc, err := client.PatchVpc(context.Background(), 1, PatchVpcRequest{RouteEntries: &entries})
+	if err != nil {
+		t.Fatalf("PatchVpc() error = %v", err)
+	}
+	if vpc.Name != "vpc" {
+		t.Errorf("Name = %q, want vpc", vpc.Name)
+	}
+}
@@ -0,0 +1,198 @@
+package cloud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Service annotations that control the load balancer of a Service.
+const (
+	annotationPrefix = "service.beta.kubernetes.io/binarylane-"
+
+	// AnnotationLoadBalancerName replaces the generated load balancer name. It
+	// is still prefixed with the cluster ID.
+	AnnotationLoadBalancerName = annotationPrefix + "loadbalancer-name"
+	// AnnotationLoadBalancerID adopts an existing load balancer instead of
+	// creating one.
+	AnnotationLoadBalancerID = annotationPrefix + "loadbalancer-id"
+	// AnnotationLoadBalancerRegion is the region slug a new load balancer is
+	// created in, or "anycast" for an anycast load balancer.
+	AnnotationLoadBalancerRegion = annotationPrefix + "loadbalancer-region"
+	// AnnotationLoadBalancerProtocols sets the entry protocol of service ports,
+	// as a comma separated list of <port>:<protocol>, where the port is a
+	// service port number or name.
+	AnnotationLoadBalancerProtocols = annotationPrefix + "loadbalancer-protocols"
+	// AnnotationHealthCheckPath is the path requested by health checks.
+	AnnotationHealthCheckPath = annotationPrefix + "loadbalancer-healthcheck-path"
+	// AnnotationHealthCheckProtocol is the protocol used by health checks,
+	// http, https or both.
+	AnnotationHealthCheckProtocol = annotationPrefix + "loadbalancer-healthcheck-protocol"
+	// AnnotationLoadBalancerNodeSelector is a label selector that limits the
+	// nodes in the load balancer pool.
+	AnnotationLoadBalancerNodeSelector = annotationPrefix + "loadbalancer-node-selector"
+	// AnnotationLoadBalancerRecreateErrored deletes and recreates the load
+	// balancer when it is errored, when set to "true".
+	AnnotationLoadBalancerRecreateErrored = annotationPrefix + "loadbalancer-recreate-errored"
+
+	// AnnotationLoadBalancerPriceHourly is set to the hourly price of the
+	// load balancer in AU$, when it is created.
+	AnnotationLoadBalancerPriceHourly = annotationPrefix + "loadbalancer-price-hourly"
+	// AnnotationLoadBalancerPriceMonthly is set to the monthly price of the
+	// load balancer in AU$, when it is created.
+	AnnotationLoadBalancerPriceMonthly = annotationPrefix + "loadbalancer-price-monthly"
+)
+
+const anycastRegion = "anycast"
+
+// serviceAnnotations is the load balancer configuration of a Service. Fields
+// are left unset when their annotation is missing or invalid.
+type serviceAnnotations struct {
+	name           string
+	loadBalancerID int64
+	region         string
+	anycast        bool
+	protocols      map[int32]binarylane.LoadBalancerRuleProtocol
+	healthCheck    *binarylane.HealthCheckRequest
+	nodeSelector   labels.Selector
+	// recreateErrored deletes an errored load balancer to create it again.
+	recreateErrored bool
+}
+
+// parseServiceAnnotations reads the load balancer annotations of a service,
+// and returns an error for each annotation with an invalid value.
+func parseServiceAnnotations(service *v1.Service) (*serviceAnnotations, []error) {
+	a := &serviceAnnotations{}
+	var errs []error
+	invalid := func(annotation, value, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("annotation %s: invalid value %q: %s", annotation, value, fmt.Sprintf(format, args...)))
+	}
+
+	if value, ok := service.Annotations[AnnotationLoadBalancerName]; ok {
+		if msgs := validation.IsDNS1123Label(value); len(msgs) > 0 {
+			invalid(AnnotationLoadBalancerName, value, "%s", strings.Join(msgs, ", "))
+		} else {
+			a.name = value
+		}
+	}
+
+	if value, ok := service.Annotations[AnnotationLoadBalancerID]; ok {
+		if id, err := strconv.ParseInt(value, 10, 64); err != nil || id <= 0 {
+			invalid(AnnotationLoadBalancerID, value, "must be a load balancer ID")
+		} else {
+			a.loadBalancerID = id
+		}
+	}
+
+	if value, ok := service.Annotations[AnnotationLoadBalancerRegion]; ok {
+		switch {
+		case value == anycastRegion:
+			a.anycast = true
+		case len(validation.IsDNS1123Label(value)) > 0:
+			invalid(AnnotationLoadBalancerRegion, value, "must be a region slug or %q", anycastRegion)
+		default:
+			a.region = value
+		}
+	}
+
+	if value, ok := service.Annotations[AnnotationLoadBalancerProtocols]; ok {
+		protocols, err := parsePortProtocols(service, value)
+		if err != nil {
+			invalid(AnnotationLoadBalancerProtocols, value, "%v", err)
+		} else {
+			a.protocols = protocols
+		}
+	}
+
+	var healthCheck binarylane.HealthCheckRequest
+	if value, ok := service.Annotations[AnnotationHealthCheckPath]; ok {
+		if !strings.HasPrefix(value, "/") {
+			invalid(AnnotationHealthCheckPath, value, "must start with /")
+		} else {
+			healthCheck.Path = &value
+		}
+	}
+	if value, ok := service.Annotations[AnnotationHealthCheckProtocol]; ok {
+		switch protocol := binarylane.HealthCheckProtocol(value); protocol {
+		case binarylane.HealthCheckProtocolHttp, binarylane.HealthCheckProtocolHttps, binarylane.HealthCheckProtocolBoth:
+			healthCheck.Protocol = &protocol
+		default:
+			invalid(AnnotationHealthCheckProtocol, value, "must be http, https or both")
+		}
+	}
+	if healthCheck.Path != nil || healthCheck.Protocol != nil {
+		a.healthCheck = &healthCheck
+	}
+
+	if value, ok := service.Annotations[AnnotationLoadBalancerNodeSelector]; ok {
+		if selector, err := labels.Parse(value); err != nil {
+			invalid(AnnotationLoadBalancerNodeSelector, value, "%v", err)
+		} else {
+			a.nodeSelector = selector
+		}
+	}
+
+	if value, ok := service.Annotations[AnnotationLoadBalancerRecreateErrored]; ok {
+		if recreate, err := strconv.ParseBool(value); err != nil {
+			invalid(AnnotationLoadBalancerRecreateErrored, value, "must be true or false")
+		} else {
+			a.recreateErrored = recreate
+		}
+	}
+
+	return a, errs
+}
+
+// parsePortProtocols parses a list of <port>:<protocol> pairs into entry
+// protocols keyed by service port number.
+func parsePortProtocols(service *v1.Service, value string) (map[int32]binarylane.LoadBalancerRuleProtocol, error) {
+	protocols := make(map[int32]binarylane.LoadBalancerRuleProtocol)
+	for pair := range strings.SplitSeq(value, ",") {
+		portName, protocolName, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not <port>:<protocol>", pair)
+		}
+
+		port, ok := servicePort(service, portName)
+		if !ok {
+			return nil, fmt.Errorf("service has no port %q", portName)
+		}
+		if _, ok := protocols[port]; ok {
+			return nil, fmt.Errorf("port %q is listed more than once", portName)
+		}
+
+		switch protocol := binarylane.LoadBalancerRuleProtocol(protocolName); protocol {
+		case binarylane.LoadBalancerRuleProtocolHttp, binarylane.LoadBalancerRuleProtocolHttps:
+			protocols[port] = protocol
+		default:
+			return nil, fmt.Errorf("protocol of port %q must be http or https", portName)
+		}
+	}
+	return protocols, nil
+}
+
+// servicePort returns the number of the service port with the given number
+// or name.
+func servicePort(service *v1.Service, portName string) (int32, bool) {
+	for _, port := range service.Spec.Ports {
+		if port.Name != "" && port.Name == portName || strconv.Itoa(int(port.Port)) == portName {
+			return port.Port, true
+		}
+	}
+	return 0, false
+}
+
+// healthCheckEqual reports whether a load balancer's health check matches the
+// fields set in a request.
+func healthCheckEqual(current binarylane.HealthCheck, desired *binarylane.HealthCheckRequest) bool {
+	if desired == nil {
+		return true
+	}
+	return (desired.Path == nil || *desired.Path == current.Path) &&
+		(desired.Protocol == nil || *desired.Protocol == current.Protocol)
+}
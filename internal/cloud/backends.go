@@ -0,0 +1,90 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	cloudproviderapi "k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
+)
+
+// controlPlaneNodeLabel marks control plane nodes, as set by kubeadm.
+const controlPlaneNodeLabel = "node-role.kubernetes.io/control-plane"
+
+// backendServerIDs returns the BinaryLane server IDs of the nodes that back
+// a service's load balancer. Nodes that have not been initialized with a
+// provider ID yet are skipped, as are nodes excluded by excludeReason and
+// nodes whose server is shut down or no longer exists. The node lifecycle
+// controller only taints nodes of shut down servers after a delay, so the
+// server status is checked as InstanceShutdown does.
+func (l *loadBalancers) backendServerIDs(ctx context.Context, service *v1.Service, annotations *serviceAnnotations, nodes []*v1.Node) ([]int64, error) {
+	serverIDs := make([]int64, 0, len(nodes))
+	for _, node := range nodes {
+		if reason := l.excludeReason(node, annotations.nodeSelector); reason != "" {
+			klog.V(4).Infof("Excluding node %s from load balancer of service %s/%s: %s", node.Name, service.Namespace, service.Name, reason)
+			continue
+		}
+
+		id, err := parseProviderID(node.Spec.ProviderID)
+		if err != nil {
+			klog.Warningf("Skipping node %s for load balancer: %v", node.Name, err)
+			continue
+		}
+		if slices.Contains(serverIDs, id) {
+			continue
+		}
+
+		server, err := l.client.GetServer(ctx, id)
+		if errors.Is(err, binarylane.ErrServerNotFound) {
+			klog.V(4).Infof("Excluding node %s from load balancer of service %s/%s: server %d not found", node.Name, service.Namespace, service.Name, id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get server %d of node %s: %w", id, node.Name, err)
+		}
+		if serverShutdown(server) {
+			klog.V(4).Infof("Excluding node %s from load balancer of service %s/%s: server is %s", node.Name, service.Namespace, service.Name, server.Status)
+			continue
+		}
+
+		serverIDs = append(serverIDs, id)
+	}
+	return serverIDs, nil
+}
+
+// excludeReason returns why a node should not receive load balancer traffic,
+// or an empty string if it should.
+func (l *loadBalancers) excludeReason(node *v1.Node, selector labels.Selector) string {
+	if _, ok := node.Labels[v1.LabelNodeExcludeBalancers]; ok {
+		return "labelled " + v1.LabelNodeExcludeBalancers
+	}
+	if _, ok := node.Labels[controlPlaneNodeLabel]; ok && l.excludeControlPlane {
+		return "control plane node"
+	}
+	if selector != nil && !selector.Matches(labels.Set(node.Labels)) {
+		return "does not match the node selector"
+	}
+	if slices.ContainsFunc(node.Spec.Taints, func(taint v1.Taint) bool {
+		return taint.Key == cloudproviderapi.TaintNodeShutdown
+	}) {
+		return "shut down"
+	}
+	if !nodeReady(node) {
+		return "not ready"
+	}
+	return ""
+}
+
+func nodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const defaultCacheTTL = 30 * time.Second
+
+// serverCache serves server and VPC reads from a snapshot of the account's
+// inventory, so that the node, route and service controllers do not each hit
+// the BinaryLane API on every reconcile. The snapshot is refreshed by a single
+// lister, either in the background or on the first read after it expires.
+// Writes pass through to the API and invalidate the affected entries.
+type serverCache struct {
+	cloudClientInterface
+	ttl time.Duration
+
+	// refreshMu ensures only one caller lists the inventory at a time.
+	refreshMu sync.Mutex
+
+	mu        sync.RWMutex
+	inventory *inventory
+	// generation is incremented by every invalidation, so that a listing
+	// that raced with a write does not restore stale VPCs or servers.
+	generation uint64
+	// evicted holds the servers written since the inventory was listed,
+	// which are read from the API until a later listing replaces them.
+	evicted map[int64]bool
+}
+
+type inventory struct {
+	fetched time.Time
+
+	servers     []binarylane.Server
+	byID        map[int64]*binarylane.Server
+	byName      map[string][]*binarylane.Server
+	byPrivateIP map[string]*binarylane.Server
+
+	// vpcs is filled on misses and pruned on writes, guarded by serverCache.mu.
+	vpcs map[int64]*binarylane.Vpc
+}
+
+func newServerCache(client cloudClientInterface, ttl time.Duration) *serverCache {
+	return &serverCache{
+		cloudClientInterface: client,
+		ttl:                  ttl,
+	}
+}
+
+func newInventory(servers []binarylane.Server, vpcs []binarylane.Vpc) *inventory {
+	inv := &inventory{
+		fetched:     time.Now(),
+		servers:     servers,
+		byID:        make(map[int64]*binarylane.Server, len(servers)),
+		byName:      make(map[string][]*binarylane.Server, len(servers)),
+		byPrivateIP: make(map[string]*binarylane.Server, len(servers)),
+		vpcs:        make(map[int64]*binarylane.Vpc, len(vpcs)),
+	}
+
+	for i := range servers {
+		server := &servers[i]
+		inv.byID[server.Id] = server
+		name := strings.ToLower(server.Name)
+		inv.byName[name] = append(inv.byName[name], server)
+		for _, ip := range privateIPs(server) {
+			inv.byPrivateIP[ip] = server
+		}
+	}
+	for i := range vpcs {
+		inv.vpcs[vpcs[i].Id] = &vpcs[i]
+	}
+
+	return inv
+}
+
+// Run keeps the inventory warm until stop is closed.
+func (s *serverCache) Run(stop <-chan struct{}) {
+	ctx := wait.ContextForChannel(stop)
+	period := max(s.ttl/2, time.Second)
+
+	wait.Until(func() {
+		if _, err := s.refresh(ctx, period); err != nil {
+			klog.Warningf("Failed to refresh BinaryLane inventory: %v", err)
+		}
+	}, period, stop)
+}
+
+// current returns a snapshot that is no older than the TTL.
+func (s *serverCache) current(ctx context.Context) (*inventory, error) {
+	s.mu.RLock()
+	inv := s.inventory
+	s.mu.RUnlock()
+
+	if inv != nil && time.Since(inv.fetched) < s.ttl {
+		return inv, nil
+	}
+	return s.refresh(ctx, s.ttl)
+}
+
+// refresh lists the inventory unless another caller refreshed it within
+// maxAge while this one was waiting.
+func (s *serverCache) refresh(ctx context.Context, maxAge time.Duration) (*inventory, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.RLock()
+	inv, generation := s.inventory, s.generation
+	s.mu.RUnlock()
+
+	if inv != nil && time.Since(inv.fetched) < maxAge {
+		return inv, nil
+	}
+
+	servers, err := s.cloudClientInterface.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vpcs, err := s.cloudClientInterface.ListVpcs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inv = newInventory(servers, vpcs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation != generation {
+		// A VPC or server was written while listing, so its listed state may
+		// be stale
+		clear(inv.vpcs)
+	} else {
+		s.evicted = nil
+	}
+	s.inventory = inv
+
+	return inv, nil
+}
+
+func (s *serverCache) GetServer(ctx context.Context, serverID int64) (*binarylane.Server, error) {
+	inv, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if server, ok := inv.byID[serverID]; ok {
+		return s.server(ctx, server)
+	}
+	// Servers created since the last listing are not known yet
+	return s.cloudClientInterface.GetServer(ctx, serverID)
+}
+
+func (s *serverCache) GetServerByName(ctx context.Context, name string) (*binarylane.Server, error) {
+	inv, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch servers := inv.byName[strings.ToLower(name)]; len(servers) {
+	case 0:
+		return s.cloudClientInterface.GetServerByName(ctx, name)
+	case 1:
+		return s.server(ctx, servers[0])
+	default:
+		matches := make([]binarylane.Server, len(servers))
+		for i, server := range servers {
+			matches[i] = *server
+		}
+		return nil, fmt.Errorf("%w: hostname %s: %s", binarylane.ErrMultipleServers, name, describeServers(matches))
+	}
+}
+
+// GetServerByPrivateIP returns the server with the given VPC address.
+func (s *serverCache) GetServerByPrivateIP(ctx context.Context, ip string) (*binarylane.Server, error) {
+	inv, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if server, ok := inv.byPrivateIP[ip]; ok {
+		return s.server(ctx, server)
+	}
+	return nil, fmt.Errorf("%w: no server with private IP %s", binarylane.ErrServerNotFound, ip)
+}
+
+// server returns a copy of a listed server, or reads it from the API when it
+// has been written since it was listed.
+func (s *serverCache) server(ctx context.Context, server *binarylane.Server) (*binarylane.Server, error) {
+	s.mu.RLock()
+	evicted := s.evicted[server.Id]
+	s.mu.RUnlock()
+	if evicted {
+		return s.cloudClientInterface.GetServer(ctx, server.Id)
+	}
+	return copyServer(server), nil
+}
+
+func (s *serverCache) ListServers(ctx context.Context) ([]binarylane.Server, error) {
+	inv, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(inv.servers), nil
+}
+
+func (s *serverCache) ListVpcs(ctx context.Context) ([]binarylane.Vpc, error) {
+	// Listing is only used to fill the cache, so it is always read through
+	return s.cloudClientInterface.ListVpcs(ctx)
+}
+
+func (s *serverCache) GetVpc(ctx context.Context, vpcID int64) (*binarylane.Vpc, error) {
+	inv, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	vpc, ok := inv.vpcs[vpcID]
+	generation := s.generation
+	s.mu.RUnlock()
+	if ok {
+		return copyVpc(vpc), nil
+	}
+
+	vpc, err = s.cloudClientInterface.GetVpc(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.generation == generation {
+		inv.vpcs[vpcID] = copyVpc(vpc)
+	}
+	s.mu.Unlock()
+
+	return vpc, nil
+}
+
+func (s *serverCache) PatchVpc(ctx context.Context, vpcID int64, req binarylane.PatchVpcRequest) (*binarylane.Vpc, error) {
+	// Invalidate even when the update fails, as it may have been applied
+	defer s.invalidateVpc(vpcID)
+	return s.cloudClientInterface.PatchVpc(ctx, vpcID, req)
+}
+
+func (s *serverCache) ChangeSourceAndDestinationCheck(ctx context.Context, serverID int64, enabled bool) (*binarylane.Action, error) {
+	// Invalidate even when the change fails, as it may have been applied
+	defer s.invalidateServer(serverID)
+	return s.cloudClientInterface.ChangeSourceAndDestinationCheck(ctx, serverID, enabled)
+}
+
+func (s *serverCache) invalidateServer(serverID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	if s.evicted == nil {
+		s.evicted = make(map[int64]bool)
+	}
+	s.evicted[serverID] = true
+}
+
+func (s *serverCache) invalidateVpc(vpcID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	if s.inventory != nil {
+		delete(s.inventory.vpcs, vpcID)
+	}
+}
+
+// Entries are shared between readers, so callers receive copies they are
+// free to modify.
+func copyServer(server *binarylane.Server) *binarylane.Server {
+	c := *server
+	return &c
+}
+
+func copyVpc(vpc *binarylane.Vpc) *binarylane.Vpc {
+	c := *vpc
+	c.RouteEntries = slices.Clone(vpc.RouteEntries)
+	return &c
+}
@@ -0,0 +1,199 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+)
+
+// countingClient counts the API calls that reach the mock client.
+type countingClient struct {
+	*mockClient
+	mu          sync.Mutex
+	listServers atomic.Int32
+	getVpc      atomic.Int32
+}
+
+func (c *countingClient) ListServers(ctx context.Context) ([]binarylane.Server, error) {
+	c.listServers.Add(1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mockClient.ListServers(ctx)
+}
+
+func (c *countingClient) GetVpc(ctx context.Context, vpcID int64) (*binarylane.Vpc, error) {
+	c.getVpc.Add(1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mockClient.GetVpc(ctx, vpcID)
+}
+
+func (c *countingClient) PatchVpc(ctx context.Context, vpcID int64, req binarylane.PatchVpcRequest) (*binarylane.Vpc, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mockClient.PatchVpc(ctx, vpcID, req)
+}
+
+func newCountingClient() *countingClient {
+	return &countingClient{
+		mockClient: &mockClient{
+			servers: map[int64]*binarylane.Server{
+				1: {
+					Id:    1,
+					Name:  "node-1",
+					VpcId: toPtr(int64(10)),
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{{IpAddress: "10.0.0.1", Type: "private"}},
+					},
+				},
+				2: {Id: 2, Name: "node-2"},
+			},
+			vpcs: map[int64]*binarylane.Vpc{
+				10: {Id: 10, Name: "vpc"},
+			},
+		},
+	}
+}
+
+func TestServerCacheLookups(t *testing.T) {
+	ctx := context.Background()
+	client := newCountingClient()
+	cache := newServerCache(client, time.Minute)
+
+	server, err := cache.GetServer(ctx, 1)
+	if err != nil || server.Name != "node-1" {
+		t.Fatalf("GetServer() = %v, %v", server, err)
+	}
+	server, err = cache.GetServerByName(ctx, "node-2")
+	if err != nil || server.Id != 2 {
+		t.Fatalf("GetServerByName() = %v, %v", server, err)
+	}
+	server, err = cache.GetServerByPrivateIP(ctx, "10.0.0.1")
+	if err != nil || server.Id != 1 {
+		t.Fatalf("GetServerByPrivateIP() = %v, %v", server, err)
+	}
+	if _, err := cache.GetServerByPrivateIP(ctx, "10.0.0.99"); !errors.Is(err, binarylane.ErrServerNotFound) {
+		t.Errorf("GetServerByPrivateIP() error = %v, want ErrServerNotFound", err)
+	}
+	if _, err := cache.GetVpc(ctx, 10); err != nil {
+		t.Fatalf("GetVpc() error = %v", err)
+	}
+
+	if got := client.listServers.Load(); got != 1 {
+		t.Errorf("ListServers calls = %d, want 1", got)
+	}
+	if got := client.getVpc.Load(); got != 0 {
+		t.Errorf("GetVpc calls = %d, want 0", got)
+	}
+
+	// Servers created since the last listing fall through to the API
+	client.servers[3] = &binarylane.Server{Id: 3, Name: "node-3"}
+	if _, err := cache.GetServer(ctx, 3); err != nil {
+		t.Errorf("GetServer() error = %v", err)
+	}
+}
+
+func TestServerCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	client := newCountingClient()
+	cache := newServerCache(client, time.Minute)
+
+	if _, err := cache.ListServers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cache.inventory.fetched = time.Now().Add(-2 * time.Minute)
+	if _, err := cache.ListServers(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.listServers.Load(); got != 2 {
+		t.Errorf("ListServers calls = %d, want 2", got)
+	}
+}
+
+func TestServerCachePatchVpcInvalidates(t *testing.T) {
+	ctx := context.Background()
+	client := newCountingClient()
+	cache := newServerCache(client, time.Minute)
+
+	vpc, err := cache.GetVpc(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []binarylane.RouteEntryRequest{{Router: "10.0.0.1", Destination: "10.244.1.0/24"}}
+	if _, err := cache.PatchVpc(ctx, 10, binarylane.PatchVpcRequest{RouteEntries: &entries}); err != nil {
+		t.Fatal(err)
+	}
+
+	vpc, err = cache.GetVpc(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vpc.RouteEntries) != 1 {
+		t.Errorf("RouteEntries = %v, want the updated entry", vpc.RouteEntries)
+	}
+	if got := client.getVpc.Load(); got != 1 {
+		t.Errorf("GetVpc calls = %d, want 1", got)
+	}
+}
+
+func TestServerCacheSourceDestCheckInvalidates(t *testing.T) {
+	ctx := context.Background()
+	client := newCountingClient()
+	cache := newServerCache(client, time.Minute)
+
+	if _, err := cache.GetServer(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.ChangeSourceAndDestinationCheck(ctx, 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := cache.GetServer(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check := server.Networks.SourceAndDestinationCheck; check == nil || *check {
+		t.Errorf("SourceAndDestinationCheck = %v, want false", check)
+	}
+	if got := client.listServers.Load(); got != 1 {
+		t.Errorf("ListServers calls = %d, want 1", got)
+	}
+
+	// A later listing has the change, and replaces the read through
+	cache.inventory.fetched = time.Now().Add(-2 * time.Minute)
+	if _, err := cache.ListServers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if cache.evicted[1] {
+		t.Error("server 1 is still evicted after a new listing")
+	}
+}
+
+func TestServerCacheConcurrentReads(t *testing.T) {
+	ctx := context.Background()
+	client := newCountingClient()
+	cache := newServerCache(client, time.Minute)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			if _, err := cache.GetServer(ctx, 1); err != nil {
+				t.Error(err)
+			}
+			if _, err := cache.GetVpc(ctx, 10); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := client.listServers.Load(); got != 1 {
+		t.Errorf("ListServers calls = %d, want a single lister", got)
+	}
+}
@@ -3,46 +3,173 @@ package cloud
 import (
 	"fmt"
 	"io"
-	"os"
+	"sync"
 
 	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	cloudprovider "k8s.io/cloud-provider"
 )
 
 const (
 	ProviderName = "binarylane"
+
+	eventComponent = "binarylane-cloud-controller-manager"
 )
 
 var _ cloudprovider.Interface = &Cloud{}
 
 type Cloud struct {
-	client *binarylane.BinaryLaneClient
-	cidr   string
+	client cloudClientInterface
+	// cache is nil when caching is disabled in the cloud config.
+	cache *serverCache
+	// routeUpdater serialises route table writes across all routes instances.
+	routeUpdater *vpcRouteUpdater
+	// blackholes remembers which blackholed routes have been reported.
+	blackholes *blackholeReporter
+	// trafficPolicies remembers the external traffic policy of each service.
+	trafficPolicies *sync.Map
+	// recorder and kubeClient are set by Initialize, and nil until then.
+	recorder   record.EventRecorder
+	kubeClient kubernetes.Interface
+	cidr       string
+
+	clusterID            string
+	region               string
+	vpcID                int64
+	nodeLookup           []NodeLookupMethod
+	zoner                *zoner
+	nodeLabels           NodeLabelsConfig
+	disableRoutes        bool
+	adoptLegacyRoutes    bool
+	keepSourceDestCheck  bool
+	disableLoadBalancers bool
+	anycastLoadBalancers bool
+	excludeControlPlane  bool
 }
 
 func newCloud(config io.Reader) (cloudprovider.Interface, error) {
-	// TODO: read config?
+	cfg, err := readConfig(config)
+	if err != nil {
+		return nil, err
+	}
 
-	token := os.Getenv("BINARYLANE_API_TOKEN")
+	token, err := cfg.token()
+	if err != nil {
+		return nil, err
+	}
 	if token == "" {
 		return nil, fmt.Errorf("BinaryLane API token is required")
 	}
 
-	client, err := binarylane.NewBinaryLaneClient(token)
+	var opts []binarylane.ClientOption
+	if cfg.APIURL != "" {
+		opts = append(opts, binarylane.WithBaseURL(cfg.APIURL))
+	}
+	if cfg.RateLimit.QPS > 0 {
+		opts = append(opts, binarylane.WithRateLimit(cfg.RateLimit.QPS, cfg.RateLimit.Burst))
+	}
+	if cfg.MaxRetries != nil {
+		opts = append(opts, binarylane.WithMaxRetries(*cfg.MaxRetries))
+	}
+
+	client, err := binarylane.NewBinaryLaneClient(token, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create BinaryLane client: %w", err)
 	}
 
-	return &Cloud{
-		client: client,
-	}, nil
+	cloud := &Cloud{
+		client:               client,
+		cidr:                 cfg.ClusterCIDR,
+		clusterID:            cfg.ClusterID,
+		region:               cfg.Region,
+		vpcID:                cfg.VpcID,
+		nodeLookup:           cfg.NodeLookup,
+		zoner:                newZoner(cfg.Zones),
+		nodeLabels:           cfg.NodeLabels,
+		disableRoutes:        !cfg.routesEnabled(),
+		adoptLegacyRoutes:    cfg.Routes.AdoptLegacyEntries,
+		keepSourceDestCheck:  !cfg.disableSourceDestinationCheck(),
+		disableLoadBalancers: !cfg.loadBalancersEnabled(),
+		anycastLoadBalancers: cfg.LoadBalancers.Anycast,
+		excludeControlPlane:  cfg.LoadBalancers.ExcludeControlPlane,
+	}
+	// Route tables are always read from the API before they are written
+	cloud.routeUpdater = newVpcRouteUpdater(client)
+	cloud.blackholes = newBlackholeReporter()
+	cloud.trafficPolicies = &sync.Map{}
+	if ttl := cfg.cacheTTL(); ttl > 0 {
+		cloud.cache = newServerCache(client, ttl)
+		cloud.client = cloud.cache
+		cloud.routeUpdater.onWrite = cloud.cache.invalidateVpc
+	}
+
+	return cloud, nil
+}
+
+// SetClusterCIDR sets the pod network range used by the routes controller,
+// typically from --cluster-cidr. A cluster CIDR from the cloud config takes
+// precedence.
+func (c *Cloud) SetClusterCIDR(cidr string) error {
+	if cidr == "" || c.cidr != "" {
+		return nil
+	}
+	if _, err := parseCIDRs(cidr); err != nil {
+		return fmt.Errorf("invalid cluster CIDR: %w", err)
+	}
+	c.cidr = cidr
+	return nil
+}
+
+// SetClusterID sets the ID used to tag BinaryLane resources owned by this
+// cluster, typically from --cluster-name. A cluster ID from the cloud config
+// takes precedence.
+func (c *Cloud) SetClusterID(clusterID string) error {
+	if clusterID == "" || c.clusterID != "" {
+		return nil
+	}
+	if err := validateClusterID(clusterID); err != nil {
+		return err
+	}
+	c.clusterID = clusterID
+	return nil
 }
 
 func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	if c.cache != nil {
+		go c.cache.Run(stop)
+	}
+
+	kubeClient := clientBuilder.ClientOrDie(eventComponent)
+	c.kubeClient = kubeClient
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartStructuredLogging(0)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	c.recorder = broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventComponent})
+	go func() {
+		<-stop
+		broadcaster.Shutdown()
+	}()
 }
 
 func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
-	return nil, false
+	if c.disableLoadBalancers {
+		return nil, false
+	}
+	return &loadBalancers{
+		client:     c.client,
+		region:     c.region,
+		clusterID:  c.clusterID,
+		anycast:    c.anycastLoadBalancers,
+		recorder:   c.recorder,
+		kubeClient: c.kubeClient,
+
+		trafficPolicies:     c.trafficPolicies,
+		excludeControlPlane: c.excludeControlPlane,
+	}, true
 }
 
 func (c *Cloud) Instances() (cloudprovider.Instances, bool) {
@@ -52,7 +179,10 @@ func (c *Cloud) Instances() (cloudprovider.Instances, bool) {
 
 func (c *Cloud) InstancesV2() (cloudprovider.InstancesV2, bool) {
 	return &instancesV2{
-		client: c.client,
+		client:     c.client,
+		nodeLookup: c.nodeLookup,
+		zoner:      c.zoner,
+		nodeLabels: c.nodeLabels,
 	}, true
 }
 
@@ -66,12 +196,21 @@ func (c *Cloud) Clusters() (cloudprovider.Clusters, bool) {
 }
 
 func (c *Cloud) Routes() (cloudprovider.Routes, bool) {
-	if c.cidr == "" {
+	if c.cidr == "" || c.disableRoutes {
 		return nil, false
 	}
 	return &routes{
-		client: c.client,
-		cidr:   c.cidr,
+		client:     c.client,
+		cidr:       c.cidr,
+		vpcID:      c.vpcID,
+		clusterID:  c.clusterID,
+		nodeLookup: c.nodeLookup,
+		updater:    c.routeUpdater,
+		blackholes: c.blackholes,
+		recorder:   c.recorder,
+
+		adoptLegacyEntries:  c.adoptLegacyRoutes,
+		keepSourceDestCheck: c.keepSourceDestCheck,
 	}, true
 }
 
@@ -80,11 +219,11 @@ func (c *Cloud) ProviderName() string {
 }
 
 func (c *Cloud) HasClusterID() bool {
-	return false
+	return c.clusterID != ""
 }
 
 func init() {
-	// TODO: register metrics here once implemented
+	registerMetrics()
 
 	cloudprovider.RegisterCloudProvider(ProviderName, newCloud)
 }
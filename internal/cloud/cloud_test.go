@@ -2,7 +2,10 @@ package cloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
 
@@ -25,8 +28,13 @@ type ClientInterface interface {
 }
 
 type mockClient struct {
-	servers map[int64]*binarylane.Server
-	vpcs    map[int64]*binarylane.Vpc
+	servers       map[int64]*binarylane.Server
+	vpcs          map[int64]*binarylane.Vpc
+	loadBalancers map[int64]*binarylane.LoadBalancer
+	actions       map[int64]*binarylane.Action
+	// availability defaults to regional load balancers in every region, and
+	// anycast load balancers.
+	availability []binarylane.LoadBalancerAvailabilityOption
 }
 
 func (m *mockClient) GetServer(ctx context.Context, serverID int64) (*binarylane.Server, error) {
@@ -37,12 +45,20 @@ func (m *mockClient) GetServer(ctx context.Context, serverID int64) (*binarylane
 }
 
 func (m *mockClient) GetServerByName(ctx context.Context, name string) (*binarylane.Server, error) {
+	var matches []*binarylane.Server
 	for _, server := range m.servers {
-		if server.Name == name {
-			return server, nil
+		if strings.EqualFold(server.Name, name) {
+			matches = append(matches, server)
 		}
 	}
-	return nil, fmt.Errorf("%w: %s", binarylane.ErrServerNotFound, name)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", binarylane.ErrServerNotFound, name)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%w: hostname %s", binarylane.ErrMultipleServers, name)
+	}
 }
 
 func (m *mockClient) ListServers(ctx context.Context) ([]binarylane.Server, error) {
@@ -53,6 +69,38 @@ func (m *mockClient) ListServers(ctx context.Context) ([]binarylane.Server, erro
 	return servers, nil
 }
 
+func (m *mockClient) ChangeSourceAndDestinationCheck(ctx context.Context, serverID int64, enabled bool) (*binarylane.Action, error) {
+	server, ok := m.servers[serverID]
+	if !ok {
+		return nil, binarylane.ErrServerNotFound
+	}
+	if action, ok := m.actions[serverID]; !ok || action.Status != binarylane.Errored {
+		server.Networks.SourceAndDestinationCheck = &enabled
+	}
+	return &binarylane.Action{Id: serverID, Type: "change_source_and_destination_check", Status: binarylane.InProgress}, nil
+}
+
+// WaitForAction completes actions immediately, unless the test set a final
+// state in actions.
+func (m *mockClient) WaitForAction(ctx context.Context, actionID int64, opts binarylane.WaitOptions) (*binarylane.Action, error) {
+	action, ok := m.actions[actionID]
+	if !ok {
+		return &binarylane.Action{Id: actionID, Status: binarylane.Completed}, nil
+	}
+	if action.Status == binarylane.Errored {
+		return action, &binarylane.ActionError{Action: *action}
+	}
+	return action, nil
+}
+
+func (m *mockClient) ListVpcs(ctx context.Context) ([]binarylane.Vpc, error) {
+	vpcs := make([]binarylane.Vpc, 0, len(m.vpcs))
+	for _, vpc := range m.vpcs {
+		vpcs = append(vpcs, *vpc)
+	}
+	return vpcs, nil
+}
+
 func (m *mockClient) GetVpc(ctx context.Context, vpcID int64) (*binarylane.Vpc, error) {
 	if vpc, ok := m.vpcs[vpcID]; ok {
 		return vpc, nil
@@ -60,13 +108,15 @@ func (m *mockClient) GetVpc(ctx context.Context, vpcID int64) (*binarylane.Vpc,
 	return nil, binarylane.ErrVpcNotFound
 }
 
-func (m *mockClient) UpdateVpc(ctx context.Context, vpcID int64, req binarylane.UpdateVpcRequest) (*binarylane.Vpc, error) {
+func (m *mockClient) PatchVpc(ctx context.Context, vpcID int64, req binarylane.PatchVpcRequest) (*binarylane.Vpc, error) {
 	vpc, ok := m.vpcs[vpcID]
 	if !ok {
 		return nil, binarylane.ErrVpcNotFound
 	}
 
-	vpc.Name = req.Name
+	if req.Name != nil {
+		vpc.Name = *req.Name
+	}
 	if req.RouteEntries != nil {
 		vpc.RouteEntries = make([]binarylane.RouteEntry, len(*req.RouteEntries))
 		for i, r := range *req.RouteEntries {
@@ -77,6 +127,134 @@ func (m *mockClient) UpdateVpc(ctx context.Context, vpcID int64, req binarylane.
 	return vpc, nil
 }
 
+func (m *mockClient) GetLoadBalancersAvailability(ctx context.Context) ([]binarylane.LoadBalancerAvailabilityOption, error) {
+	if m.availability != nil {
+		return m.availability, nil
+	}
+	return []binarylane.LoadBalancerAvailabilityOption{
+		{Regions: &[]string{"syd", "mel", "bne", "per"}, PriceHourly: 0.015, PriceMonthly: 10},
+		{Anycast: true, PriceHourly: 0.03, PriceMonthly: 20},
+	}, nil
+}
+
+func (m *mockClient) ListLoadBalancers(ctx context.Context) ([]binarylane.LoadBalancer, error) {
+	var lbs []binarylane.LoadBalancer
+	for _, lb := range m.loadBalancers {
+		lbs = append(lbs, *lb)
+	}
+	return lbs, nil
+}
+
+func (m *mockClient) GetLoadBalancer(ctx context.Context, loadBalancerID int64) (*binarylane.LoadBalancer, error) {
+	lb, ok := m.loadBalancers[loadBalancerID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", binarylane.ErrLoadBalancerNotFound, loadBalancerID)
+	}
+	return lb, nil
+}
+
+func (m *mockClient) GetLoadBalancerByName(ctx context.Context, name string) (*binarylane.LoadBalancer, error) {
+	for _, lb := range m.loadBalancers {
+		if lb.Name == name {
+			return lb, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", binarylane.ErrLoadBalancerNotFound, name)
+}
+
+func (m *mockClient) CreateLoadBalancer(ctx context.Context, req binarylane.CreateLoadBalancerRequest) (*binarylane.LoadBalancer, error) {
+	if m.loadBalancers == nil {
+		m.loadBalancers = make(map[int64]*binarylane.LoadBalancer)
+	}
+
+	lb := &binarylane.LoadBalancer{
+		Id:     int64(len(m.loadBalancers) + 1),
+		Name:   req.Name,
+		Ip:     fmt.Sprintf("203.0.113.%d", len(m.loadBalancers)+1),
+		Status: binarylane.LoadBalancerStatusActive,
+	}
+	if req.Region != nil {
+		lb.Region = &binarylane.Region{Slug: *req.Region}
+	}
+	if req.ForwardingRules != nil {
+		for _, r := range *req.ForwardingRules {
+			lb.ForwardingRules = append(lb.ForwardingRules, binarylane.ForwardingRule(r))
+		}
+	}
+	lb.HealthCheck = mockHealthCheck(req.HealthCheck)
+	if req.ServerIds != nil {
+		lb.ServerIds = append([]int64(nil), *req.ServerIds...)
+	}
+
+	m.loadBalancers[lb.Id] = lb
+	return lb, nil
+}
+
+func (m *mockClient) UpdateLoadBalancer(ctx context.Context, loadBalancerID int64, req binarylane.UpdateLoadBalancerRequest) (*binarylane.LoadBalancer, error) {
+	lb, ok := m.loadBalancers[loadBalancerID]
+	if !ok {
+		return nil, binarylane.ErrLoadBalancerNotFound
+	}
+
+	lb.Name = req.Name
+	lb.ForwardingRules = nil
+	if req.ForwardingRules != nil {
+		for _, r := range *req.ForwardingRules {
+			lb.ForwardingRules = append(lb.ForwardingRules, binarylane.ForwardingRule(r))
+		}
+	}
+	lb.HealthCheck = mockHealthCheck(req.HealthCheck)
+	lb.ServerIds = nil
+	if req.ServerIds != nil {
+		lb.ServerIds = append([]int64(nil), *req.ServerIds...)
+	}
+
+	return lb, nil
+}
+
+// mockHealthCheck applies the API's defaults to a health check request.
+func mockHealthCheck(req *binarylane.HealthCheckRequest) binarylane.HealthCheck {
+	hc := binarylane.HealthCheck{Path: "/", Protocol: binarylane.HealthCheckProtocolHttp}
+	if req == nil {
+		return hc
+	}
+	if req.Path != nil {
+		hc.Path = *req.Path
+	}
+	if req.Protocol != nil {
+		hc.Protocol = *req.Protocol
+	}
+	return hc
+}
+
+func (m *mockClient) AddLoadBalancerServers(ctx context.Context, loadBalancerID int64, serverIDs []int64) error {
+	lb, ok := m.loadBalancers[loadBalancerID]
+	if !ok {
+		return binarylane.ErrLoadBalancerNotFound
+	}
+	lb.ServerIds = append(lb.ServerIds, serverIDs...)
+	return nil
+}
+
+func (m *mockClient) RemoveLoadBalancerServers(ctx context.Context, loadBalancerID int64, serverIDs []int64) error {
+	lb, ok := m.loadBalancers[loadBalancerID]
+	if !ok {
+		return binarylane.ErrLoadBalancerNotFound
+	}
+	lb.ServerIds = slices.DeleteFunc(lb.ServerIds, func(id int64) bool {
+		return slices.Contains(serverIDs, id)
+	})
+	return nil
+}
+
+func (m *mockClient) DeleteLoadBalancer(ctx context.Context, loadBalancerID int64) error {
+	if _, ok := m.loadBalancers[loadBalancerID]; !ok {
+		return binarylane.ErrLoadBalancerNotFound
+	}
+	delete(m.loadBalancers, loadBalancerID)
+	return nil
+}
+
 func TestInstanceMetadata(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -174,6 +352,31 @@ func TestInstanceMetadata(t *testing.T) {
 			wantHostLabel:     "physical-host-02",
 			wantServerIDLabel: "789",
 		},
+		{
+			name: "dual-stack server",
+			server: &binarylane.Server{
+				Id:     321,
+				Name:   "dual-stack-node",
+				Size:   binarylane.Size{Slug: "std-2vcpu"},
+				Region: binarylane.Region{Slug: "syd"},
+				VpcId:  toPtr(int64(1)),
+				Networks: binarylane.Networks{
+					V4: []binarylane.Network{
+						{IpAddress: "43.229.63.58", Type: "public"},
+						{IpAddress: "172.24.41.234", Type: "private"},
+					},
+					V6: []binarylane.Network{
+						{IpAddress: "2401:fc00::10", Type: "public"},
+						{IpAddress: "fd00:24::10", Type: "private"},
+					},
+				},
+			},
+			wantInternalIPs:   []string{"172.24.41.234", "fd00:24::10"},
+			wantExternalIPs:   []string{"43.229.63.58", "2401:fc00::10"},
+			wantProviderID:    "binarylane://321",
+			wantInstanceType:  "std-2vcpu",
+			wantServerIDLabel: "321",
+		},
 	}
 
 	for _, tt := range tests {
@@ -251,6 +454,236 @@ func TestInstanceMetadata(t *testing.T) {
 	}
 }
 
+func TestInstanceMetadataNodeLabels(t *testing.T) {
+	server := &binarylane.Server{
+		Id:     123,
+		Name:   "test-node",
+		Vcpus:  4,
+		Memory: 8192,
+		Disk:   80,
+		Image: binarylane.Image{
+			Distribution: toPtr("Ubuntu"),
+			Slug:         toPtr("ubuntu-24.04"),
+		},
+		AdvancedFeatures: binarylane.AdvancedServerFeatures{
+			MachineType:             toPtr(binarylane.VmMachineType("pc_i440fx_8point2")),
+			ProcessorModel:          toPtr(int64(7)),
+			EnabledAdvancedFeatures: []binarylane.AdvancedFeature{binarylane.NestedVirt, binarylane.CloudInit},
+		},
+		Features:         []string{"ipv6", "private_networking"},
+		Host:             binarylane.Host{DisplayName: "physical-host-01"},
+		VpcId:            toPtr(int64(42)),
+		NextBackupWindow: &binarylane.BackupWindow{},
+		PartnerId:        toPtr(int64(124)),
+	}
+
+	tests := []struct {
+		name       string
+		nodeLabels NodeLabelsConfig
+		want       map[string]string
+	}{
+		{
+			name: "no labels by default",
+			want: map[string]string{
+				"binarylane.com/host": "physical-host-01",
+			},
+		},
+		{
+			name: "every label",
+			nodeLabels: NodeLabelsConfig{
+				Include: nodeLabels,
+			},
+			want: map[string]string{
+				"binarylane.com/host":                         "physical-host-01",
+				"binarylane.com/vcpus":                        "4",
+				"binarylane.com/memory-mb":                    "8192",
+				"binarylane.com/disk-gb":                      "80",
+				"binarylane.com/image-distribution":           "Ubuntu",
+				"binarylane.com/image-slug":                   "ubuntu-24.04",
+				"binarylane.com/machine-type":                 "pc_i440fx_8point2",
+				"binarylane.com/processor-model":              "7",
+				"binarylane.com/advanced-feature-nested-virt": "true",
+				"binarylane.com/advanced-feature-cloud-init":  "true",
+				"binarylane.com/feature-ipv6":                 "true",
+				"binarylane.com/feature-private_networking":   "true",
+				"binarylane.com/vpc-id":                       "42",
+				"binarylane.com/backups":                      "true",
+				"binarylane.com/partner-id":                   "124",
+			},
+		},
+		{
+			name: "allowlist and prefix",
+			nodeLabels: NodeLabelsConfig{
+				Include: []NodeLabel{NodeLabelVcpus, NodeLabelMemory, NodeLabelBackups},
+				Prefix:  toPtr("hw.example.com/"),
+			},
+			want: map[string]string{
+				"binarylane.com/host":      "physical-host-01",
+				"hw.example.com/vcpus":     "4",
+				"hw.example.com/memory-mb": "8192",
+				"hw.example.com/backups":   "true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.nodeLabels.validate(); err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+
+			inst := &instancesV2{
+				client: &mockClient{
+					servers: map[int64]*binarylane.Server{server.Id: server},
+				},
+				nodeLabels: tt.nodeLabels,
+			}
+			node := &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: server.Name},
+				Spec:       v1.NodeSpec{ProviderID: "binarylane://123"},
+			}
+
+			metadata, err := inst.InstanceMetadata(context.Background(), node)
+			if err != nil {
+				t.Fatalf("InstanceMetadata() error = %v", err)
+			}
+			if !maps.Equal(metadata.AdditionalLabels, tt.want) {
+				t.Errorf("AdditionalLabels = %v, want %v", metadata.AdditionalLabels, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeLabelsOmitUnsetValues(t *testing.T) {
+	cfg := NodeLabelsConfig{Include: nodeLabels}
+	labels := cfg.labels(&binarylane.Server{
+		Image:    binarylane.Image{Distribution: toPtr("Windows Server 2022")},
+		Features: []string{"backups"},
+	})
+
+	want := map[string]string{
+		"binarylane.com/vcpus":              "0",
+		"binarylane.com/memory-mb":          "0",
+		"binarylane.com/disk-gb":            "0",
+		"binarylane.com/image-distribution": "Windows-Server-2022",
+		"binarylane.com/feature-backups":    "true",
+		"binarylane.com/backups":            "true",
+	}
+	if !maps.Equal(labels, want) {
+		t.Errorf("labels() = %v, want %v", labels, want)
+	}
+}
+
+func TestNodeLabelsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     NodeLabelsConfig
+		wantErr bool
+	}{
+		{name: "empty prefix", cfg: NodeLabelsConfig{Prefix: toPtr("")}},
+		{name: "unknown label", cfg: NodeLabelsConfig{Include: []NodeLabel{"colour"}}, wantErr: true},
+		{name: "duplicate label", cfg: NodeLabelsConfig{Include: []NodeLabel{NodeLabelDisk, NodeLabelDisk}}, wantErr: true},
+		{name: "prefix without slash", cfg: NodeLabelsConfig{Prefix: toPtr("binarylane.com")}, wantErr: true},
+		{name: "invalid prefix", cfg: NodeLabelsConfig{Prefix: toPtr("Binary Lane/")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetServerForNode(t *testing.T) {
+	privateNetwork := func(ip string) binarylane.Networks {
+		return binarylane.Networks{V4: []binarylane.Network{{IpAddress: ip, Type: "private"}}}
+	}
+	servers := map[int64]*binarylane.Server{
+		1: {Id: 1, Name: "worker-1", Networks: privateNetwork("10.0.0.1")},
+		2: {Id: 2, Name: "worker-2.example.com", Networks: privateNetwork("10.0.0.2"), Permalink: toPtr("happy-otter")},
+		3: {Id: 3, Name: "dup", Networks: privateNetwork("10.0.0.3")},
+		4: {Id: 4, Name: "dup.other.example.com", Networks: privateNetwork("10.0.0.4")},
+	}
+
+	tests := []struct {
+		name       string
+		node       *v1.Node
+		nodeLookup []NodeLookupMethod
+		wantID     int64
+		wantErr    error
+	}{
+		{
+			name:   "provider ID",
+			node:   &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "anything"}, Spec: v1.NodeSpec{ProviderID: "binarylane://2"}},
+			wantID: 2,
+		},
+		{
+			name:   "exact hostname",
+			node:   &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+			wantID: 1,
+		},
+		{
+			name:   "short hostname",
+			node:   &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1.cluster.local"}},
+			wantID: 1,
+		},
+		{
+			name: "internal IP",
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "ip-override"},
+				Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+					{Type: v1.NodeExternalIP, Address: "10.0.0.1"},
+					{Type: v1.NodeInternalIP, Address: "10.0.0.2"},
+				}},
+			},
+			wantID: 2,
+		},
+		{
+			name:   "permalink",
+			node:   &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "happy-otter"}},
+			wantID: 2,
+		},
+		{
+			name:    "ambiguous short hostname",
+			node:    &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "dup.cluster.local"}},
+			wantErr: binarylane.ErrMultipleServers,
+		},
+		{
+			name:       "method not in chain",
+			node:       &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "happy-otter"}},
+			nodeLookup: []NodeLookupMethod{NodeLookupProviderID, NodeLookupHostname},
+			wantErr:    binarylane.ErrServerNotFound,
+		},
+		{
+			name:    "no match",
+			node:    &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "missing"}},
+			wantErr: binarylane.ErrServerNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := &instancesV2{client: &mockClient{servers: servers}, nodeLookup: tt.nodeLookup}
+
+			server, err := inst.getServerForNode(context.Background(), tt.node)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("getServerForNode() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getServerForNode() error = %v", err)
+			}
+			if server.Id != tt.wantID {
+				t.Errorf("getServerForNode() = %d, want %d", server.Id, tt.wantID)
+			}
+		})
+	}
+}
+
 func TestParseProviderID(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -325,6 +758,31 @@ func TestRoutesWithCIDR(t *testing.T) {
 	}
 }
 
+func TestSetClusterCIDR(t *testing.T) {
+	cloud := &Cloud{}
+	if err := cloud.SetClusterCIDR("10.244.0.0/16"); err != nil {
+		t.Fatalf("SetClusterCIDR() error = %v", err)
+	}
+	if cloud.cidr != "10.244.0.0/16" {
+		t.Errorf("cidr = %s, want 10.244.0.0/16", cloud.cidr)
+	}
+	if _, enabled := cloud.Routes(); !enabled {
+		t.Error("Routes() should be enabled after SetClusterCIDR")
+	}
+
+	// Cluster CIDR from the cloud config takes precedence
+	if err := cloud.SetClusterCIDR("10.96.0.0/12"); err != nil {
+		t.Fatalf("SetClusterCIDR() error = %v", err)
+	}
+	if cloud.cidr != "10.244.0.0/16" {
+		t.Errorf("cidr = %s, want 10.244.0.0/16", cloud.cidr)
+	}
+
+	if err := (&Cloud{}).SetClusterCIDR("not-a-cidr"); err == nil {
+		t.Error("SetClusterCIDR() expected error for invalid CIDR")
+	}
+}
+
 func TestNewCloud_RequiresToken(t *testing.T) {
 	t.Setenv("BINARYLANE_API_TOKEN", "")
 
@@ -0,0 +1,364 @@
+package cloud
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	cloudConfigAPIVersion = "cloud.binarylane.com/v1alpha1"
+	cloudConfigKind       = "CloudConfig"
+)
+
+// NodeLookupMethod is one step of the chain used to find the BinaryLane
+// server backing a Kubernetes node.
+type NodeLookupMethod string
+
+const (
+	// NodeLookupProviderID uses the server ID in the node's provider ID.
+	NodeLookupProviderID NodeLookupMethod = "providerID"
+	// NodeLookupHostname matches the node name against server hostnames.
+	NodeLookupHostname NodeLookupMethod = "hostname"
+	// NodeLookupShortHostname matches the first label of the node name and
+	// server hostname, so that a node named "worker-1.example.com" matches
+	// the server "worker-1".
+	NodeLookupShortHostname NodeLookupMethod = "shortHostname"
+	// NodeLookupInternalIP matches the node's InternalIP addresses against
+	// server private addresses.
+	NodeLookupInternalIP NodeLookupMethod = "internalIP"
+	// NodeLookupPermalink matches the node name against server permalinks.
+	NodeLookupPermalink NodeLookupMethod = "permalink"
+)
+
+// ZoneSource is how the topology zone of a node is derived from its server.
+type ZoneSource string
+
+const (
+	// ZoneSourceRegion uses the region slug as the zone.
+	ZoneSourceRegion ZoneSource = "region"
+	// ZoneSourceHost places each group of physical hosts in its own zone,
+	// named <region>-<group>, so that replicas can be spread across hosts.
+	ZoneSourceHost ZoneSource = "host"
+	// ZoneSourceRegionMap looks the region slug up in ZonesConfig.Regions.
+	ZoneSourceRegionMap ZoneSource = "regionMap"
+)
+
+var zoneSources = []ZoneSource{ZoneSourceRegion, ZoneSourceHost, ZoneSourceRegionMap}
+
+// NodeLabel is a group of node labels derived from the server.
+type NodeLabel string
+
+const (
+	// NodeLabelVcpus adds <prefix>vcpus.
+	NodeLabelVcpus NodeLabel = "vcpus"
+	// NodeLabelMemory adds <prefix>memory-mb.
+	NodeLabelMemory NodeLabel = "memory"
+	// NodeLabelDisk adds <prefix>disk-gb.
+	NodeLabelDisk NodeLabel = "disk"
+	// NodeLabelImage adds <prefix>image-distribution and <prefix>image-slug.
+	NodeLabelImage NodeLabel = "image"
+	// NodeLabelAdvancedFeatures adds <prefix>machine-type,
+	// <prefix>processor-model and <prefix>advanced-feature-<name> for each
+	// enabled advanced feature.
+	NodeLabelAdvancedFeatures NodeLabel = "advancedFeatures"
+	// NodeLabelFeatures adds <prefix>feature-<name> for each enabled feature.
+	NodeLabelFeatures NodeLabel = "features"
+	// NodeLabelVpc adds <prefix>vpc-id.
+	NodeLabelVpc NodeLabel = "vpc"
+	// NodeLabelBackups adds <prefix>backups.
+	NodeLabelBackups NodeLabel = "backups"
+	// NodeLabelPartner adds <prefix>partner-id.
+	NodeLabelPartner NodeLabel = "partner"
+)
+
+var nodeLabels = []NodeLabel{
+	NodeLabelVcpus,
+	NodeLabelMemory,
+	NodeLabelDisk,
+	NodeLabelImage,
+	NodeLabelAdvancedFeatures,
+	NodeLabelFeatures,
+	NodeLabelVpc,
+	NodeLabelBackups,
+	NodeLabelPartner,
+}
+
+const defaultNodeLabelPrefix = "binarylane.com/"
+
+var defaultNodeLookup = []NodeLookupMethod{
+	NodeLookupProviderID,
+	NodeLookupHostname,
+	NodeLookupShortHostname,
+	NodeLookupInternalIP,
+	NodeLookupPermalink,
+}
+
+// CloudConfig is the file passed to the cloud controller manager with
+// --cloud-config.
+//
+//	apiVersion: cloud.binarylane.com/v1alpha1
+//	kind: CloudConfig
+//	apiTokenFile: /etc/binarylane/api-token
+//	clusterCIDR: 10.244.0.0/16
+type CloudConfig struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// APIToken is the BinaryLane API token. The BINARYLANE_API_TOKEN
+	// environment variable takes precedence when set.
+	APIToken string `json:"apiToken,omitempty"`
+	// APITokenFile is a path to a file containing the BinaryLane API token.
+	APITokenFile string `json:"apiTokenFile,omitempty"`
+	// APIURL overrides the BinaryLane API endpoint.
+	APIURL string `json:"apiURL,omitempty"`
+	// RateLimit is the client-side limit for BinaryLane API requests, shared
+	// by all controllers.
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	// MaxRetries is how many times a failed idempotent API request is retried.
+	MaxRetries *int `json:"maxRetries,omitempty"`
+	// CacheTTL is how long server and VPC listings are reused between API
+	// calls. Zero disables the cache. Defaults to 30s.
+	CacheTTL *metav1.Duration `json:"cacheTTL,omitempty"`
+
+	// ClusterID identifies the cluster that owns BinaryLane resources.
+	ClusterID string `json:"clusterID,omitempty"`
+	// ClusterCIDR is the pod network range used by the routes controller.
+	ClusterCIDR string `json:"clusterCIDR,omitempty"`
+	// Region is the default region for new resources, such as load balancers.
+	Region string `json:"region,omitempty"`
+	// VpcID restricts route management to a single VPC.
+	VpcID int64 `json:"vpcID,omitempty"`
+
+	Routes        RoutesConfig        `json:"routes"`
+	LoadBalancers LoadBalancersConfig `json:"loadBalancers"`
+	Zones         ZonesConfig         `json:"zones"`
+	NodeLabels    NodeLabelsConfig    `json:"nodeLabels"`
+
+	// NodeLookup is the order in which methods are tried to find the server
+	// backing a node. Defaults to every method, in the order they are declared.
+	NodeLookup []NodeLookupMethod `json:"nodeLookup,omitempty"`
+}
+
+type RateLimitConfig struct {
+	// QPS is the sustained number of API requests per second.
+	QPS float64 `json:"qps,omitempty"`
+	// Burst is the number of API requests that may be made at once.
+	Burst int `json:"burst,omitempty"`
+}
+
+type RoutesConfig struct {
+	// Enabled turns the routes implementation on or off. Routes are still only
+	// enabled when a cluster CIDR is known. Defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+	// AdoptLegacyEntries manages route entries without an ownership marker,
+	// as created by older versions, when they route part of the cluster CIDR
+	// through a server in the VPC. Other unmarked entries are never touched.
+	AdoptLegacyEntries bool `json:"adoptLegacyEntries,omitempty"`
+	// DisableSourceDestinationCheck turns off the source and destination
+	// check of servers that routes point at, which otherwise drops pod
+	// traffic. Defaults to true.
+	DisableSourceDestinationCheck *bool `json:"disableSourceDestinationCheck,omitempty"`
+}
+
+type ZonesConfig struct {
+	// Source is how node zones are derived. Defaults to region.
+	Source ZoneSource `json:"source,omitempty"`
+	// HostGroupPattern is a regular expression matched against the host's
+	// display name by the host source. The first capture group, or the whole
+	// match without one, names the host group. Hosts that do not match, and
+	// all hosts when unset, are grouped by their whole name.
+	HostGroupPattern string `json:"hostGroupPattern,omitempty"`
+	// Regions maps region slugs to zones for the regionMap source. Regions
+	// that are not listed use the region slug.
+	Regions map[string]string `json:"regions,omitempty"`
+}
+
+type NodeLabelsConfig struct {
+	// Include lists the labels added to nodes, in addition to the host and
+	// standard topology labels. Defaults to none.
+	Include []NodeLabel `json:"include,omitempty"`
+	// Prefix is prepended to the label names. Defaults to binarylane.com/.
+	Prefix *string `json:"prefix,omitempty"`
+}
+
+type LoadBalancersConfig struct {
+	// Enabled turns the load balancer implementation on or off. Defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Anycast creates anycast load balancers, which are not bound to a
+	// region, for Services that do not set a region by annotation.
+	Anycast bool `json:"anycast,omitempty"`
+	// ExcludeControlPlane keeps nodes labelled
+	// node-role.kubernetes.io/control-plane out of load balancer pools.
+	ExcludeControlPlane bool `json:"excludeControlPlane,omitempty"`
+}
+
+// readConfig parses and validates the cloud config. A nil or empty reader
+// yields the default config.
+func readConfig(r io.Reader) (*CloudConfig, error) {
+	cfg := &CloudConfig{}
+
+	if r != nil {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cloud config: %w", err)
+		}
+		if len(strings.TrimSpace(string(data))) > 0 {
+			if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+				return nil, fmt.Errorf("failed to parse cloud config: %w", err)
+			}
+			if err := cfg.validateVersion(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(cfg.NodeLookup) == 0 {
+		cfg.NodeLookup = defaultNodeLookup
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid cloud config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func (c *CloudConfig) validateVersion() error {
+	if c.APIVersion != cloudConfigAPIVersion {
+		return fmt.Errorf("unsupported cloud config apiVersion %q (expected %q)", c.APIVersion, cloudConfigAPIVersion)
+	}
+	if c.Kind != cloudConfigKind {
+		return fmt.Errorf("unsupported cloud config kind %q (expected %q)", c.Kind, cloudConfigKind)
+	}
+	return nil
+}
+
+func (c *CloudConfig) validate() error {
+	var errs []error
+
+	if c.APIToken != "" && c.APITokenFile != "" {
+		errs = append(errs, errors.New("apiToken and apiTokenFile are mutually exclusive"))
+	}
+
+	if c.APIURL != "" {
+		u, err := url.Parse(c.APIURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("apiURL %q must be an absolute http or https URL", c.APIURL))
+		}
+	}
+
+	if c.RateLimit.QPS < 0 {
+		errs = append(errs, fmt.Errorf("rateLimit.qps %v must not be negative", c.RateLimit.QPS))
+	}
+	if c.RateLimit.Burst < 0 {
+		errs = append(errs, fmt.Errorf("rateLimit.burst %d must not be negative", c.RateLimit.Burst))
+	}
+	if c.MaxRetries != nil && *c.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("maxRetries %d must not be negative", *c.MaxRetries))
+	}
+
+	if c.CacheTTL != nil && c.CacheTTL.Duration < 0 {
+		errs = append(errs, fmt.Errorf("cacheTTL %s must not be negative", c.CacheTTL.Duration))
+	}
+
+	if c.ClusterID != "" {
+		if err := validateClusterID(c.ClusterID); err != nil {
+			errs = append(errs, fmt.Errorf("clusterID: %w", err))
+		}
+	}
+
+	if c.ClusterCIDR != "" {
+		if _, err := parseCIDRs(c.ClusterCIDR); err != nil {
+			errs = append(errs, fmt.Errorf("clusterCIDR: %w", err))
+		}
+	}
+
+	if c.VpcID < 0 {
+		errs = append(errs, fmt.Errorf("vpcID %d must not be negative", c.VpcID))
+	}
+
+	if err := c.NodeLabels.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("nodeLabels: %w", err))
+	}
+
+	if err := c.Zones.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("zones: %w", err))
+	}
+
+	for i, method := range c.NodeLookup {
+		if !slices.Contains(defaultNodeLookup, method) {
+			errs = append(errs, fmt.Errorf("nodeLookup method %q must be one of %q", method, defaultNodeLookup))
+		} else if slices.Contains(c.NodeLookup[:i], method) {
+			errs = append(errs, fmt.Errorf("nodeLookup method %q is listed more than once", method))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// token returns the API token, preferring the BINARYLANE_API_TOKEN
+// environment variable over the config file.
+func (c *CloudConfig) token() (string, error) {
+	if token := os.Getenv("BINARYLANE_API_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	if c.APITokenFile != "" {
+		data, err := os.ReadFile(c.APITokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read API token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	return c.APIToken, nil
+}
+
+// parseCIDRs parses a comma-separated list of CIDRs. A dual-stack list has
+// one IPv4 and one IPv6 CIDR, as with the --cluster-cidr flag.
+func parseCIDRs(cidrs string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, cidr := range strings.Split(cidrs, ",") {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid CIDR: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	switch {
+	case len(prefixes) > 2:
+		return nil, fmt.Errorf("%q has more than two CIDRs, a dual-stack cluster CIDR has one IPv4 and one IPv6 CIDR", cidrs)
+	case len(prefixes) == 2 && prefixes[0].Addr().Is4() == prefixes[1].Addr().Is4():
+		return nil, fmt.Errorf("%q has two CIDRs of the same family, a dual-stack cluster CIDR has one IPv4 and one IPv6 CIDR", cidrs)
+	}
+	return prefixes, nil
+}
+
+func (c *CloudConfig) cacheTTL() time.Duration {
+	if c.CacheTTL == nil {
+		return defaultCacheTTL
+	}
+	return c.CacheTTL.Duration
+}
+
+func (c *CloudConfig) routesEnabled() bool {
+	return c.Routes.Enabled == nil || *c.Routes.Enabled
+}
+
+func (c *CloudConfig) disableSourceDestinationCheck() bool {
+	return c.Routes.DisableSourceDestinationCheck == nil || *c.Routes.DisableSourceDestinationCheck
+}
+
+func (c *CloudConfig) loadBalancersEnabled() bool {
+	return c.LoadBalancers.Enabled == nil || *c.LoadBalancers.Enabled
+}
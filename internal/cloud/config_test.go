@@ -0,0 +1,208 @@
+package cloud
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+		check   func(t *testing.T, cfg *CloudConfig)
+	}{
+		{
+			name:   "empty config uses defaults",
+			config: "",
+			check: func(t *testing.T, cfg *CloudConfig) {
+				if !slices.Equal(cfg.NodeLookup, defaultNodeLookup) {
+					t.Errorf("NodeLookup = %v, want %v", cfg.NodeLookup, defaultNodeLookup)
+				}
+				if !cfg.routesEnabled() || !cfg.loadBalancersEnabled() {
+					t.Errorf("expected routes and load balancers to be enabled by default")
+				}
+			},
+		},
+		{
+			name: "full config",
+			config: `
+apiVersion: cloud.binarylane.com/v1alpha1
+kind: CloudConfig
+apiToken: secret
+apiURL: https://api.example.com/v2
+rateLimit:
+  qps: 2.5
+  burst: 5
+maxRetries: 2
+cacheTTL: 1m
+clusterID: prod
+clusterCIDR: 10.244.0.0/16
+region: syd
+vpcID: 42
+routes:
+  enabled: false
+  adoptLegacyEntries: true
+loadBalancers:
+  enabled: true
+nodeLookup:
+  - providerID
+  - internalIP
+`,
+			check: func(t *testing.T, cfg *CloudConfig) {
+				if cfg.APIToken != "secret" {
+					t.Errorf("APIToken = %q, want secret", cfg.APIToken)
+				}
+				if cfg.ClusterCIDR != "10.244.0.0/16" {
+					t.Errorf("ClusterCIDR = %q, want 10.244.0.0/16", cfg.ClusterCIDR)
+				}
+				if cfg.RateLimit.QPS != 2.5 || cfg.RateLimit.Burst != 5 {
+					t.Errorf("RateLimit = %+v, want qps 2.5 burst 5", cfg.RateLimit)
+				}
+				if cfg.MaxRetries == nil || *cfg.MaxRetries != 2 {
+					t.Errorf("MaxRetries = %v, want 2", cfg.MaxRetries)
+				}
+				if cfg.cacheTTL() != time.Minute {
+					t.Errorf("cacheTTL() = %s, want 1m", cfg.cacheTTL())
+				}
+				if cfg.VpcID != 42 {
+					t.Errorf("VpcID = %d, want 42", cfg.VpcID)
+				}
+				if cfg.routesEnabled() {
+					t.Errorf("expected routes to be disabled")
+				}
+				if !cfg.Routes.AdoptLegacyEntries {
+					t.Errorf("expected legacy route entries to be adopted")
+				}
+				if want := []NodeLookupMethod{NodeLookupProviderID, NodeLookupInternalIP}; !slices.Equal(cfg.NodeLookup, want) {
+					t.Errorf("NodeLookup = %v, want %v", cfg.NodeLookup, want)
+				}
+			},
+		},
+		{
+			name:    "missing apiVersion",
+			config:  "apiToken: secret\n",
+			wantErr: "unsupported cloud config apiVersion",
+		},
+		{
+			name: "unknown field",
+			config: `
+apiVersion: cloud.binarylane.com/v1alpha1
+kind: CloudConfig
+apiTokn: secret
+`,
+			wantErr: "unknown field",
+		},
+		{
+			name: "invalid values",
+			config: `
+apiVersion: cloud.binarylane.com/v1alpha1
+kind: CloudConfig
+apiToken: secret
+apiTokenFile: /etc/token
+apiURL: not-a-url
+clusterCIDR: 10.244.0.0
+nodeLookup: [fuzzy]
+`,
+			wantErr: "mutually exclusive",
+		},
+		{
+			name: "dual-stack cluster CIDR of one family",
+			config: `
+apiVersion: cloud.binarylane.com/v1alpha1
+kind: CloudConfig
+clusterCIDR: 10.244.0.0/16,10.245.0.0/16
+`,
+			wantErr: "same family",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := readConfig(strings.NewReader(tt.config))
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readConfig() error = %v", err)
+			}
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+func TestReadConfigNilReader(t *testing.T) {
+	cfg, err := readConfig(nil)
+	if err != nil {
+		t.Fatalf("readConfig() error = %v", err)
+	}
+	if cfg == nil {
+		t.Fatal("expected default config")
+	}
+}
+
+func TestConfigToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("BINARYLANE_API_TOKEN", "")
+	cfg := &CloudConfig{APITokenFile: tokenFile}
+	token, err := cfg.token()
+	if err != nil {
+		t.Fatalf("token() error = %v", err)
+	}
+	if token != "from-file" {
+		t.Errorf("token() = %q, want from-file", token)
+	}
+
+	t.Setenv("BINARYLANE_API_TOKEN", "from-env")
+	token, err = cfg.token()
+	if err != nil {
+		t.Fatalf("token() error = %v", err)
+	}
+	if token != "from-env" {
+		t.Errorf("token() = %q, want from-env", token)
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		cidrs   string
+		want    int
+		wantErr bool
+	}{
+		{cidrs: "10.244.0.0/16", want: 1},
+		{cidrs: "fd00:10:244::/56", want: 1},
+		{cidrs: "10.244.0.0/16, fd00:10:244::/56", want: 2},
+		{cidrs: "fd00:10:244::/56,10.244.0.0/16", want: 2},
+		{cidrs: "fd00:10:244::/56,fd00:10:245::/56", wantErr: true},
+		{cidrs: "10.244.0.0/16,fd00:10:244::/56,10.245.0.0/16", wantErr: true},
+		{cidrs: "10.244.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cidrs, func(t *testing.T) {
+			prefixes, err := parseCIDRs(tt.cidrs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCIDRs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(prefixes) != tt.want {
+				t.Errorf("parseCIDRs() = %v, want %d prefixes", prefixes, tt.want)
+			}
+		})
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
 	v1 "k8s.io/api/core/v1"
@@ -18,15 +20,32 @@ type cloudClientInterface interface {
 	GetServer(ctx context.Context, serverID int64) (*binarylane.Server, error)
 	GetServerByName(ctx context.Context, name string) (*binarylane.Server, error)
 	ListServers(ctx context.Context) ([]binarylane.Server, error)
+	ChangeSourceAndDestinationCheck(ctx context.Context, serverID int64, enabled bool) (*binarylane.Action, error)
+	WaitForAction(ctx context.Context, actionID int64, opts binarylane.WaitOptions) (*binarylane.Action, error)
+	ListVpcs(ctx context.Context) ([]binarylane.Vpc, error)
 	GetVpc(ctx context.Context, vpcID int64) (*binarylane.Vpc, error)
-	UpdateVpc(ctx context.Context, vpcID int64, req binarylane.UpdateVpcRequest) (*binarylane.Vpc, error)
+	PatchVpc(ctx context.Context, vpcID int64, req binarylane.PatchVpcRequest) (*binarylane.Vpc, error)
+	GetLoadBalancersAvailability(ctx context.Context) ([]binarylane.LoadBalancerAvailabilityOption, error)
+	ListLoadBalancers(ctx context.Context) ([]binarylane.LoadBalancer, error)
+	GetLoadBalancer(ctx context.Context, loadBalancerID int64) (*binarylane.LoadBalancer, error)
+	GetLoadBalancerByName(ctx context.Context, name string) (*binarylane.LoadBalancer, error)
+	CreateLoadBalancer(ctx context.Context, req binarylane.CreateLoadBalancerRequest) (*binarylane.LoadBalancer, error)
+	UpdateLoadBalancer(ctx context.Context, loadBalancerID int64, req binarylane.UpdateLoadBalancerRequest) (*binarylane.LoadBalancer, error)
+	AddLoadBalancerServers(ctx context.Context, loadBalancerID int64, serverIDs []int64) error
+	RemoveLoadBalancerServers(ctx context.Context, loadBalancerID int64, serverIDs []int64) error
+	DeleteLoadBalancer(ctx context.Context, loadBalancerID int64) error
 }
 
 type instancesV2 struct {
-	client cloudClientInterface
+	client     cloudClientInterface
+	nodeLookup []NodeLookupMethod
+	zoner      *zoner
+	nodeLabels NodeLabelsConfig
 }
 
-func (i *instancesV2) InstanceExists(ctx context.Context, node *v1.Node) (bool, error) {
+func (i *instancesV2) InstanceExists(ctx context.Context, node *v1.Node) (_ bool, err error) {
+	defer recordOperation("InstanceExists", time.Now(), &err)
+
 	server, err := i.getServerForNode(ctx, node)
 	if err != nil {
 		if errors.Is(err, binarylane.ErrServerNotFound) {
@@ -37,16 +56,25 @@ func (i *instancesV2) InstanceExists(ctx context.Context, node *v1.Node) (bool,
 	return server != nil, nil
 }
 
-func (i *instancesV2) InstanceShutdown(ctx context.Context, node *v1.Node) (bool, error) {
+func (i *instancesV2) InstanceShutdown(ctx context.Context, node *v1.Node) (_ bool, err error) {
+	defer recordOperation("InstanceShutdown", time.Now(), &err)
+
 	server, err := i.getServerForNode(ctx, node)
 	if err != nil {
 		return false, err
 	}
 
-	return server.Status == "off" || server.Status == "archive", nil
+	return serverShutdown(server), nil
+}
+
+// serverShutdown reports whether a server is powered off or archived.
+func serverShutdown(server *binarylane.Server) bool {
+	return server.Status == "off" || server.Status == "archive"
 }
 
-func (i *instancesV2) InstanceMetadata(ctx context.Context, node *v1.Node) (*cloudprovider.InstanceMetadata, error) {
+func (i *instancesV2) InstanceMetadata(ctx context.Context, node *v1.Node) (_ *cloudprovider.InstanceMetadata, err error) {
+	defer recordOperation("InstanceMetadata", time.Now(), &err)
+
 	server, err := i.getServerForNode(ctx, node)
 	if err != nil {
 		return nil, err
@@ -77,7 +105,13 @@ func (i *instancesV2) InstanceMetadata(ctx context.Context, node *v1.Node) (*clo
 	}
 
 	for _, net := range server.Networks.V6 {
-		if net.Type == "public" {
+		switch net.Type {
+		case "private":
+			addresses = append(addresses, v1.NodeAddress{
+				Type:    v1.NodeInternalIP,
+				Address: net.IpAddress,
+			})
+		case "public":
 			addresses = append(addresses, v1.NodeAddress{
 				Type:    v1.NodeExternalIP,
 				Address: net.IpAddress,
@@ -90,26 +124,20 @@ func (i *instancesV2) InstanceMetadata(ctx context.Context, node *v1.Node) (*clo
 	if server.Host.DisplayName != "" {
 		labels["binarylane.com/host"] = server.Host.DisplayName
 	}
+	maps.Copy(labels, i.nodeLabels.labels(server))
 
 	return &cloudprovider.InstanceMetadata{
 		ProviderID:       providerID,
 		NodeAddresses:    addresses,
 		InstanceType:     server.Size.Slug,
-		Zone:             server.Region.Slug,
+		Zone:             i.zoner.zone(server),
 		Region:           server.Region.Slug,
 		AdditionalLabels: labels,
 	}, nil
 }
 
 func (i *instancesV2) getServerForNode(ctx context.Context, node *v1.Node) (*binarylane.Server, error) {
-	if node.Spec.ProviderID != "" {
-		id, err := parseProviderID(node.Spec.ProviderID)
-		if err == nil {
-			return i.client.GetServer(ctx, id)
-		}
-	}
-
-	return i.client.GetServerByName(ctx, node.Name)
+	return findServer(ctx, i.client, i.nodeLookup, node)
 }
 
 func parseProviderID(providerID string) (int64, error) {
@@ -0,0 +1,117 @@
+package cloud
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// invalidLabelChars matches runs of characters not allowed in label values.
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (n *NodeLabelsConfig) validate() error {
+	var errs []error
+
+	for i, label := range n.Include {
+		if !slices.Contains(nodeLabels, label) {
+			errs = append(errs, fmt.Errorf("label %q must be one of %q", label, nodeLabels))
+		} else if slices.Contains(n.Include[:i], label) {
+			errs = append(errs, fmt.Errorf("label %q is listed more than once", label))
+		}
+	}
+
+	prefix := n.prefix()
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		errs = append(errs, fmt.Errorf("prefix %q must be empty, or a DNS subdomain followed by a slash", prefix))
+	} else if msgs := validation.IsQualifiedName(prefix + "memory-mb"); len(msgs) > 0 {
+		errs = append(errs, fmt.Errorf("prefix %q is invalid: %s", prefix, strings.Join(msgs, ", ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n *NodeLabelsConfig) prefix() string {
+	if n.Prefix == nil {
+		return defaultNodeLabelPrefix
+	}
+	return *n.Prefix
+}
+
+// labels returns the allowed labels derived from the server. Labels with no
+// value for the server, such as the partner ID of a server without a
+// partner, are left out.
+func (n *NodeLabelsConfig) labels(server *binarylane.Server) map[string]string {
+	labels := make(map[string]string)
+	prefix := n.prefix()
+	set := func(name, value string) {
+		if value = labelValue(value); value != "" {
+			labels[prefix+labelValue(name)] = value
+		}
+	}
+
+	for _, label := range n.Include {
+		switch label {
+		case NodeLabelVcpus:
+			set("vcpus", strconv.Itoa(int(server.Vcpus)))
+		case NodeLabelMemory:
+			set("memory-mb", strconv.Itoa(int(server.Memory)))
+		case NodeLabelDisk:
+			set("disk-gb", strconv.Itoa(int(server.Disk)))
+		case NodeLabelImage:
+			if server.Image.Distribution != nil {
+				set("image-distribution", *server.Image.Distribution)
+			}
+			if server.Image.Slug != nil {
+				set("image-slug", *server.Image.Slug)
+			}
+		case NodeLabelAdvancedFeatures:
+			features := server.AdvancedFeatures
+			if features.MachineType != nil {
+				set("machine-type", string(*features.MachineType))
+			}
+			if features.ProcessorModel != nil {
+				set("processor-model", strconv.FormatInt(*features.ProcessorModel, 10))
+			}
+			for _, feature := range features.EnabledAdvancedFeatures {
+				set("advanced-feature-"+string(feature), "true")
+			}
+		case NodeLabelFeatures:
+			for _, feature := range server.Features {
+				set("feature-"+feature, "true")
+			}
+		case NodeLabelVpc:
+			if server.VpcId != nil {
+				set("vpc-id", strconv.FormatInt(*server.VpcId, 10))
+			}
+		case NodeLabelBackups:
+			set("backups", strconv.FormatBool(backupsEnabled(server)))
+		case NodeLabelPartner:
+			if server.PartnerId != nil {
+				set("partner-id", strconv.FormatInt(*server.PartnerId, 10))
+			}
+		}
+	}
+
+	return labels
+}
+
+// backupsEnabled reports whether the server has scheduled backups.
+func backupsEnabled(server *binarylane.Server) bool {
+	return server.NextBackupWindow != nil || slices.Contains(server.Features, "backups")
+}
+
+// labelValue replaces characters that are not allowed in a label value, and
+// truncates it to the maximum label length.
+func labelValue(s string) string {
+	s = invalidLabelChars.ReplaceAllString(s, "-")
+	if len(s) > validation.LabelValueMaxLength {
+		s = s[:validation.LabelValueMaxLength]
+	}
+	return strings.Trim(s, "-_.")
+}
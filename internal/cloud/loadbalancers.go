@@ -0,0 +1,599 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	cloudprovider "k8s.io/cloud-provider"
+	cloudproviderapi "k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
+)
+
+var _ cloudprovider.LoadBalancer = &loadBalancers{}
+
+const (
+	loadBalancerWaitInterval = 5 * time.Second
+	loadBalancerWaitTimeout  = time.Minute
+	loadBalancerRetryAfter   = 15 * time.Second
+)
+
+type loadBalancers struct {
+	client    cloudClientInterface
+	region    string
+	clusterID string
+	anycast   bool
+	// excludeControlPlane keeps control plane nodes out of the pool.
+	excludeControlPlane bool
+	// recorder reports problems with services, and may be nil.
+	recorder record.EventRecorder
+	// kubeClient records load balancer prices on services, and may be nil.
+	kubeClient kubernetes.Interface
+	// trafficPolicies holds the external traffic policy of each service at its
+	// last sync, keyed by UID, so that a change of policy is noticed. It is
+	// shared by every loadBalancers instance of a cloud, and may be nil.
+	trafficPolicies *sync.Map
+
+	// waitInterval and waitTimeout bound how long EnsureLoadBalancer polls a
+	// new load balancer, and default to loadBalancerWaitInterval and
+	// loadBalancerWaitTimeout.
+	waitInterval time.Duration
+	waitTimeout  time.Duration
+}
+
+func (l *loadBalancers) GetLoadBalancer(ctx context.Context, clusterName string, service *v1.Service) (_ *v1.LoadBalancerStatus, _ bool, err error) {
+	defer recordOperation("GetLoadBalancer", time.Now(), &err)
+
+	annotations, _ := parseServiceAnnotations(service)
+	lb, err := l.findLoadBalancer(ctx, service, l.loadBalancerName(service, annotations), annotations)
+	if err != nil {
+		if errors.Is(err, binarylane.ErrLoadBalancerNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get load balancer: %w", err)
+	}
+
+	return loadBalancerStatus(lb), true, nil
+}
+
+// GetLoadBalancerName prefixes the default name, or the name from the
+// service's annotation, with the cluster ID, so that a load balancer can only
+// be found by the cluster that created it.
+func (l *loadBalancers) GetLoadBalancerName(ctx context.Context, clusterName string, service *v1.Service) string {
+	annotations, _ := parseServiceAnnotations(service)
+	return l.loadBalancerName(service, annotations)
+}
+
+func (l *loadBalancers) loadBalancerName(service *v1.Service, annotations *serviceAnnotations) string {
+	name := annotations.name
+	if name == "" {
+		name = cloudprovider.DefaultLoadBalancerName(service)
+	}
+	if l.clusterID == "" {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", l.clusterID, name)
+}
+
+func (l *loadBalancers) EnsureLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (_ *v1.LoadBalancerStatus, err error) {
+	defer recordOperation("EnsureLoadBalancer", time.Now(), &err)
+
+	annotations, err := l.parseAnnotations(service)
+	if err != nil {
+		return nil, err
+	}
+	name := l.loadBalancerName(service, annotations)
+
+	rules, err := forwardingRules(service, annotations.protocols)
+	if err != nil {
+		l.warn(service, "UnsupportedServicePorts", err)
+		return nil, err
+	}
+	serverIDs, err := l.backendServerIDs(ctx, service, annotations, nodes)
+	if err != nil {
+		return nil, err
+	}
+	healthCheck := annotations.healthCheck
+	policyChanged := l.trafficPolicyChanged(service)
+
+	lb, err := l.findLoadBalancer(ctx, service, name, annotations)
+	if err != nil && !errors.Is(err, binarylane.ErrLoadBalancerNotFound) {
+		return nil, fmt.Errorf("failed to get load balancer: %w", err)
+	}
+
+	if lb != nil && lb.Status == binarylane.LoadBalancerStatusErrored {
+		if err := l.recreateErrored(ctx, service, annotations, lb); err != nil {
+			return nil, err
+		}
+		lb = nil
+	}
+
+	if lb == nil {
+		if annotations.loadBalancerID != 0 {
+			// Creating a load balancer would not adopt the one that was asked for
+			return nil, fmt.Errorf("load balancer %d from annotation %s: %w", annotations.loadBalancerID, AnnotationLoadBalancerID, err)
+		}
+
+		region, err := l.createRegion(ctx, annotations, serverIDs)
+		if err != nil {
+			return nil, err
+		}
+		option, err := l.checkAvailability(ctx, service, region)
+		if err != nil {
+			return nil, err
+		}
+		l.warnLocalTrafficPolicy(service, annotations)
+
+		lb, err = l.client.CreateLoadBalancer(ctx, binarylane.CreateLoadBalancerRequest{
+			Name:            name,
+			Region:          region,
+			ForwardingRules: &rules,
+			HealthCheck:     healthCheck,
+			ServerIds:       &serverIDs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create load balancer %s: %w", name, err)
+		}
+		klog.Infof("Created load balancer %s (%d) for service %s/%s", name, lb.Id, service.Namespace, service.Name)
+		l.recordPrice(ctx, service, option)
+
+		if lb, err = l.waitForActive(ctx, service, lb); err != nil {
+			return nil, err
+		}
+		return loadBalancerStatus(lb), nil
+	}
+
+	if lb, err = l.waitForActive(ctx, service, lb); err != nil {
+		return nil, err
+	}
+	if policyChanged {
+		l.warnLocalTrafficPolicy(service, annotations)
+	}
+
+	// An adopted load balancer is renamed, which stamps it with the cluster ID,
+	// as is one whose name annotation was changed
+	if lb.Name != name || !forwardingRulesEqual(lb.ForwardingRules, rules) || !healthCheckEqual(lb.HealthCheck, healthCheck) {
+		currentServerIDs := lb.ServerIds
+		lb, err = l.client.UpdateLoadBalancer(ctx, lb.Id, binarylane.UpdateLoadBalancerRequest{
+			Name:            name,
+			ForwardingRules: &rules,
+			HealthCheck:     healthCheck,
+			ServerIds:       &currentServerIDs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update load balancer %s: %w", name, err)
+		}
+	}
+
+	if err := l.syncServers(ctx, lb, serverIDs); err != nil {
+		return nil, err
+	}
+
+	return loadBalancerStatus(lb), nil
+}
+
+func (l *loadBalancers) UpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (err error) {
+	defer recordOperation("UpdateLoadBalancer", time.Now(), &err)
+
+	// A node selector is parsed strictly, as ignoring it would add every node
+	annotations, err := l.parseAnnotations(service)
+	if err != nil {
+		return err
+	}
+	name := l.loadBalancerName(service, annotations)
+
+	lb, err := l.findLoadBalancer(ctx, service, name, annotations)
+	if err != nil {
+		return fmt.Errorf("failed to get load balancer %s: %w", name, err)
+	}
+
+	serverIDs, err := l.backendServerIDs(ctx, service, annotations, nodes)
+	if err != nil {
+		return err
+	}
+
+	return l.syncServers(ctx, lb, serverIDs)
+}
+
+func (l *loadBalancers) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string, service *v1.Service) (err error) {
+	defer recordOperation("EnsureLoadBalancerDeleted", time.Now(), &err)
+
+	// Ignoring an invalid name or ID would look up the wrong load balancer,
+	// and leak the one that was created or adopted
+	annotations, err := l.parseAnnotations(service)
+	if err != nil {
+		return err
+	}
+	name := l.loadBalancerName(service, annotations)
+
+	lb, err := l.findLoadBalancer(ctx, service, name, annotations)
+	if err != nil {
+		if errors.Is(err, binarylane.ErrLoadBalancerNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get load balancer %s: %w", name, err)
+	}
+
+	if err := l.client.DeleteLoadBalancer(ctx, lb.Id); err != nil {
+		if errors.Is(err, binarylane.ErrLoadBalancerNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete load balancer %s: %w", name, err)
+	}
+	klog.Infof("Deleted load balancer %s (%d) for service %s/%s", lb.Name, lb.Id, service.Namespace, service.Name)
+	if l.trafficPolicies != nil {
+		l.trafficPolicies.Delete(service.UID)
+	}
+
+	return nil
+}
+
+// parseAnnotations parses the service's annotations, recording an event on
+// the service for each invalid value. EnsureLoadBalancer, UpdateLoadBalancer
+// and EnsureLoadBalancerDeleted reject invalid annotations. GetLoadBalancer
+// and GetLoadBalancerName ignore them, as they do not change anything.
+func (l *loadBalancers) parseAnnotations(service *v1.Service) (*serviceAnnotations, error) {
+	annotations, errs := parseServiceAnnotations(service)
+	// The API rejects names longer than a DNS label, including the prefix
+	if annotations.name != "" {
+		if name := l.loadBalancerName(service, annotations); len(name) > validation.DNS1123LabelMaxLength {
+			errs = append(errs, fmt.Errorf("annotation %s: invalid value %q: name %s is longer than %d characters with the cluster ID prefix",
+				AnnotationLoadBalancerName, annotations.name, name, validation.DNS1123LabelMaxLength))
+		}
+	}
+	if len(errs) == 0 {
+		return annotations, nil
+	}
+
+	for _, err := range errs {
+		l.warn(service, "InvalidAnnotation", err)
+	}
+	return nil, fmt.Errorf("service %s/%s: %w", service.Namespace, service.Name, errors.Join(errs...))
+}
+
+// warn logs a problem with a service and records it as an event.
+func (l *loadBalancers) warn(service *v1.Service, reason string, err error) {
+	klog.Warningf("Service %s/%s: %s: %v", service.Namespace, service.Name, reason, err)
+	if l.recorder != nil {
+		l.recorder.Event(service, v1.EventTypeWarning, reason, err.Error())
+	}
+}
+
+// waitForActive polls a load balancer that is still being provisioned until
+// it is active. When it is not active within the wait timeout, a retry error
+// is returned, so that the service controller checks again after
+// loadBalancerRetryAfter instead of backing off.
+func (l *loadBalancers) waitForActive(ctx context.Context, service *v1.Service, lb *binarylane.LoadBalancer) (*binarylane.LoadBalancer, error) {
+	if lb.Status != binarylane.LoadBalancerStatusNew {
+		return l.checkErrored(service, lb)
+	}
+
+	interval, timeout := l.waitInterval, l.waitTimeout
+	if interval <= 0 {
+		interval = loadBalancerWaitInterval
+	}
+	if timeout <= 0 {
+		timeout = loadBalancerWaitTimeout
+	}
+
+	klog.Infof("Waiting for load balancer %s (%d) of service %s/%s to become active", lb.Name, lb.Id, service.Namespace, service.Name)
+	if l.recorder != nil {
+		l.recorder.Eventf(service, v1.EventTypeNormal, "WaitingForLoadBalancer", "Waiting for load balancer %s (%d) to become active", lb.Name, lb.Id)
+	}
+
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, false, func(ctx context.Context) (bool, error) {
+		current, err := l.client.GetLoadBalancer(ctx, lb.Id)
+		if err != nil {
+			return false, err
+		}
+		lb = current
+		return lb.Status != binarylane.LoadBalancerStatusNew, nil
+	})
+	if wait.Interrupted(err) && ctx.Err() == nil {
+		return nil, cloudproviderapi.NewRetryError(fmt.Sprintf("load balancer %s (%d) is still being provisioned", lb.Name, lb.Id), loadBalancerRetryAfter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get load balancer %s: %w", lb.Name, err)
+	}
+
+	return l.checkErrored(service, lb)
+}
+
+// checkErrored reports a load balancer that failed to provision.
+func (l *loadBalancers) checkErrored(service *v1.Service, lb *binarylane.LoadBalancer) (*binarylane.LoadBalancer, error) {
+	if lb.Status != binarylane.LoadBalancerStatusErrored {
+		return lb, nil
+	}
+	err := fmt.Errorf("load balancer %s (%d) is errored, set the %s annotation to \"true\" to recreate it", lb.Name, lb.Id, AnnotationLoadBalancerRecreateErrored)
+	l.warn(service, "LoadBalancerErrored", err)
+	return nil, err
+}
+
+// recreateErrored deletes an errored load balancer, so that it is created
+// again, when the service opts in with an annotation.
+func (l *loadBalancers) recreateErrored(ctx context.Context, service *v1.Service, annotations *serviceAnnotations, lb *binarylane.LoadBalancer) error {
+	if !annotations.recreateErrored {
+		_, err := l.checkErrored(service, lb)
+		return err
+	}
+	if annotations.loadBalancerID != 0 {
+		// A new load balancer would not have the adopted ID
+		err := fmt.Errorf("load balancer %s (%d) is errored, and cannot be recreated as it was adopted with the %s annotation", lb.Name, lb.Id, AnnotationLoadBalancerID)
+		l.warn(service, "LoadBalancerErrored", err)
+		return err
+	}
+
+	if err := l.client.DeleteLoadBalancer(ctx, lb.Id); err != nil && !errors.Is(err, binarylane.ErrLoadBalancerNotFound) {
+		return fmt.Errorf("failed to delete errored load balancer %s: %w", lb.Name, err)
+	}
+	klog.Infof("Deleted errored load balancer %s (%d) of service %s/%s to recreate it", lb.Name, lb.Id, service.Namespace, service.Name)
+	if l.recorder != nil {
+		l.recorder.Eventf(service, v1.EventTypeWarning, "RecreatingLoadBalancer", "Deleted errored load balancer %s (%d) to recreate it", lb.Name, lb.Id)
+	}
+
+	return nil
+}
+
+// findLoadBalancer returns the load balancer adopted by the service, or
+// otherwise the one with the given name. When the name annotation has been
+// changed, no load balancer has the new name yet, so the service's current
+// load balancer is found by its ingress IP, to be renamed rather than leaked.
+func (l *loadBalancers) findLoadBalancer(ctx context.Context, service *v1.Service, name string, annotations *serviceAnnotations) (*binarylane.LoadBalancer, error) {
+	if annotations.loadBalancerID != 0 {
+		return l.client.GetLoadBalancer(ctx, annotations.loadBalancerID)
+	}
+	lb, err := l.client.GetLoadBalancerByName(ctx, name)
+	if !errors.Is(err, binarylane.ErrLoadBalancerNotFound) {
+		return lb, err
+	}
+
+	var ips []string
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			ips = append(ips, ingress.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, err
+	}
+	lbs, listErr := l.client.ListLoadBalancers(ctx)
+	if listErr != nil {
+		return nil, fmt.Errorf("failed to list load balancers: %w", listErr)
+	}
+	for i := range lbs {
+		// Only a load balancer stamped with the cluster ID is taken over
+		owned := l.clusterID == "" || strings.HasPrefix(lbs[i].Name, l.clusterID+"-")
+		if owned && slices.Contains(ips, lbs[i].Ip) {
+			klog.Infof("Found load balancer %s (%d) of service %s/%s by its IP %s, expected name %s", lbs[i].Name, lbs[i].Id, service.Namespace, service.Name, lbs[i].Ip, name)
+			return &lbs[i], nil
+		}
+	}
+	return nil, err
+}
+
+// syncServers adds and removes servers so that the load balancer pool matches
+// serverIDs, without touching the rest of the load balancer configuration.
+func (l *loadBalancers) syncServers(ctx context.Context, lb *binarylane.LoadBalancer, serverIDs []int64) error {
+	var toAdd, toRemove []int64
+	for _, id := range serverIDs {
+		if !slices.Contains(lb.ServerIds, id) {
+			toAdd = append(toAdd, id)
+		}
+	}
+	for _, id := range lb.ServerIds {
+		if !slices.Contains(serverIDs, id) {
+			toRemove = append(toRemove, id)
+		}
+	}
+
+	if len(toAdd) > 0 {
+		if err := l.client.AddLoadBalancerServers(ctx, lb.Id, toAdd); err != nil {
+			return fmt.Errorf("failed to add servers to load balancer %s: %w", lb.Name, err)
+		}
+	}
+	if len(toRemove) > 0 {
+		if err := l.client.RemoveLoadBalancerServers(ctx, lb.Id, toRemove); err != nil {
+			return fmt.Errorf("failed to remove servers from load balancer %s: %w", lb.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// createRegion returns the region a new load balancer is created in, or nil
+// for an anycast load balancer. A region annotation takes precedence over the
+// anycast mode of the cloud config.
+func (l *loadBalancers) createRegion(ctx context.Context, annotations *serviceAnnotations, serverIDs []int64) (*string, error) {
+	switch {
+	case annotations.region != "":
+		return &annotations.region, nil
+	case annotations.anycast || l.anycast:
+		return nil, nil
+	}
+
+	region, err := l.loadBalancerRegion(ctx, serverIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &region, nil
+}
+
+// checkAvailability returns the load balancer option for a region, or for an
+// anycast load balancer when region is nil. When there is no such option, it
+// is reported as an event on the service, before anything is created.
+func (l *loadBalancers) checkAvailability(ctx context.Context, service *v1.Service, region *string) (*binarylane.LoadBalancerAvailabilityOption, error) {
+	options, err := l.client.GetLoadBalancersAvailability(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get load balancer availability: %w", err)
+	}
+	if option := availabilityOption(options, region); option != nil {
+		return option, nil
+	}
+
+	var regions []string
+	for _, option := range options {
+		if option.Regions != nil {
+			regions = append(regions, *option.Regions...)
+		}
+	}
+	slices.Sort(regions)
+
+	if region == nil {
+		err = fmt.Errorf("anycast load balancers are not available, set a region with the %s annotation", AnnotationLoadBalancerRegion)
+	} else {
+		err = fmt.Errorf("load balancers are not available in region %s, available regions: %s", *region, strings.Join(slices.Compact(regions), ", "))
+	}
+	l.warn(service, "LoadBalancerUnavailable", err)
+
+	return nil, fmt.Errorf("service %s/%s: %w", service.Namespace, service.Name, err)
+}
+
+func availabilityOption(options []binarylane.LoadBalancerAvailabilityOption, region *string) *binarylane.LoadBalancerAvailabilityOption {
+	for i, option := range options {
+		if region == nil && option.Anycast ||
+			region != nil && !option.Anycast && option.Regions != nil && slices.Contains(*option.Regions, *region) {
+			return &options[i]
+		}
+	}
+	return nil
+}
+
+// recordPrice annotates the service with the price of its load balancer. The
+// price is informational, so failing to record it does not fail the sync.
+func (l *loadBalancers) recordPrice(ctx context.Context, service *v1.Service, option *binarylane.LoadBalancerAvailabilityOption) {
+	if l.kubeClient == nil {
+		return
+	}
+
+	prices := map[string]string{
+		AnnotationLoadBalancerPriceHourly:  strconv.FormatFloat(option.PriceHourly, 'f', -1, 64),
+		AnnotationLoadBalancerPriceMonthly: strconv.FormatFloat(option.PriceMonthly, 'f', -1, 64),
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": prices},
+	})
+	if err != nil {
+		klog.Warningf("Failed to record load balancer price on service %s/%s: %v", service.Namespace, service.Name, err)
+		return
+	}
+	_, err = l.kubeClient.CoreV1().Services(service.Namespace).Patch(ctx, service.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		klog.Warningf("Failed to record load balancer price on service %s/%s: %v", service.Namespace, service.Name, err)
+	}
+}
+
+// loadBalancerRegion returns the configured default region, or otherwise the
+// region of the first backend server, which is where a new load balancer is
+// created.
+func (l *loadBalancers) loadBalancerRegion(ctx context.Context, serverIDs []int64) (string, error) {
+	if l.region != "" {
+		return l.region, nil
+	}
+	if len(serverIDs) == 0 {
+		return "", fmt.Errorf("no nodes with a BinaryLane provider ID available to determine load balancer region")
+	}
+
+	server, err := l.client.GetServer(ctx, serverIDs[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to get server %d: %w", serverIDs[0], err)
+	}
+
+	return server.Region.Slug, nil
+}
+
+// forwardingRules maps service ports onto load balancer forwarding rules.
+// BinaryLane load balancers forward by entry protocol only, listening on 80
+// for HTTP and 443 for HTTPS. Port 80 is treated as HTTP and port 443 as
+// HTTPS, and any other port is rejected unless its protocol is set by
+// annotation.
+func forwardingRules(service *v1.Service, protocols map[int32]binarylane.LoadBalancerRuleProtocol) ([]binarylane.ForwardingRuleRequest, error) {
+	var rules []binarylane.ForwardingRuleRequest
+	for _, port := range service.Spec.Ports {
+		if port.Protocol != "" && port.Protocol != v1.ProtocolTCP {
+			return nil, fmt.Errorf("service %s/%s port %d: protocol %s is not supported by BinaryLane load balancers", service.Namespace, service.Name, port.Port, port.Protocol)
+		}
+
+		protocol, ok := protocols[port.Port]
+		switch {
+		case ok:
+		case port.Port == 80:
+			protocol = binarylane.LoadBalancerRuleProtocolHttp
+		case port.Port == 443:
+			protocol = binarylane.LoadBalancerRuleProtocolHttps
+		default:
+			// Serving the port on 80 or 443 would leave the advertised port closed
+			return nil, fmt.Errorf("service %s/%s port %d: BinaryLane load balancers only listen on ports 80 and 443, set the entry protocol of other ports with the %s annotation", service.Namespace, service.Name, port.Port, AnnotationLoadBalancerProtocols)
+		}
+
+		rule := binarylane.ForwardingRuleRequest{EntryProtocol: protocol}
+		if !slices.Contains(rules, rule) {
+			rules = append(rules, rule)
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("service %s/%s has no ports", service.Namespace, service.Name)
+	}
+
+	return rules, nil
+}
+
+// trafficPolicyChanged records the external traffic policy of a service, and
+// reports whether it differs from the policy of the previous sync. The first
+// sync after a restart is not reported as a change.
+func (l *loadBalancers) trafficPolicyChanged(service *v1.Service) bool {
+	if l.trafficPolicies == nil {
+		return false
+	}
+	previous, ok := l.trafficPolicies.Swap(service.UID, service.Spec.ExternalTrafficPolicy)
+	return ok && previous != service.Spec.ExternalTrafficPolicy
+}
+
+// warnLocalTrafficPolicy warns that externalTrafficPolicy: Local is not
+// honoured. BinaryLane health checks have no port of their own and are sent
+// to the forwarding port of each server, so they cannot target the
+// healthCheckNodePort of the Service, and nodes without a local endpoint stay
+// in the pool. It is only called when a load balancer is created or the policy
+// changes, as it would otherwise be recorded on every sync.
+func (l *loadBalancers) warnLocalTrafficPolicy(service *v1.Service, annotations *serviceAnnotations) {
+	if service.Spec.ExternalTrafficPolicy != v1.ServiceExternalTrafficPolicyLocal || annotations.healthCheck != nil {
+		return
+	}
+	l.warn(service, "HealthCheckNodePortUnsupported", fmt.Errorf(
+		"externalTrafficPolicy: Local is not supported, as load balancer health checks cannot target kube-proxy's healthCheckNodePort %d, so nodes without a local endpoint stay in the pool; set the %s annotation to check a path on the forwarding port instead",
+		service.Spec.HealthCheckNodePort, AnnotationHealthCheckPath))
+}
+
+func forwardingRulesEqual(current []binarylane.ForwardingRule, desired []binarylane.ForwardingRuleRequest) bool {
+	if len(current) != len(desired) {
+		return false
+	}
+	for _, rule := range desired {
+		if !slices.Contains(current, binarylane.ForwardingRule(rule)) {
+			return false
+		}
+	}
+	return true
+}
+
+func loadBalancerStatus(lb *binarylane.LoadBalancer) *v1.LoadBalancerStatus {
+	status := &v1.LoadBalancerStatus{}
+	if lb.Ip != "" {
+		status.Ingress = []v1.LoadBalancerIngress{{IP: lb.Ip}}
+	}
+	return status
+}
@@ -0,0 +1,1029 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	cloudprovider "k8s.io/cloud-provider"
+	cloudproviderapi "k8s.io/cloud-provider/api"
+)
+
+func testService(ports ...int32) *v1.Service {
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			UID:       types.UID("11111111-2222-3333-4444-555555555555"),
+		},
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeLoadBalancer,
+		},
+	}
+	for _, port := range ports {
+		service.Spec.Ports = append(service.Spec.Ports, v1.ServicePort{
+			Port:     port,
+			Protocol: v1.ProtocolTCP,
+		})
+	}
+	return service
+}
+
+func testNode(name string, serverID int64) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.NodeSpec{
+			ProviderID: fmt.Sprintf("binarylane://%d", serverID),
+		},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			},
+		},
+	}
+}
+
+func TestEnsureLoadBalancer(t *testing.T) {
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {Id: 1, Name: "node-1", Region: binarylane.Region{Slug: "syd"}},
+			2: {Id: 2, Name: "node-2", Region: binarylane.Region{Slug: "syd"}},
+		},
+	}
+	lbs := &loadBalancers{client: mock}
+	service := testService(80, 443)
+
+	status, err := lbs.EnsureLoadBalancer(context.Background(), "test-cluster", service, []*v1.Node{
+		testNode("node-1", 1),
+		testNode("node-2", 2),
+	})
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer() error = %v", err)
+	}
+
+	if len(mock.loadBalancers) != 1 {
+		t.Fatalf("expected 1 load balancer, got %d", len(mock.loadBalancers))
+	}
+	lb := mock.loadBalancers[1]
+
+	if lb.Name != cloudprovider.DefaultLoadBalancerName(service) {
+		t.Errorf("Name = %s, want %s", lb.Name, cloudprovider.DefaultLoadBalancerName(service))
+	}
+	if lb.Region == nil || lb.Region.Slug != "syd" {
+		t.Errorf("Region = %v, want syd", lb.Region)
+	}
+	if len(lb.ForwardingRules) != 2 {
+		t.Errorf("ForwardingRules = %v, want http and https", lb.ForwardingRules)
+	}
+	if len(status.Ingress) != 1 || status.Ingress[0].IP != lb.Ip {
+		t.Errorf("Ingress = %v, want %s", status.Ingress, lb.Ip)
+	}
+
+	// Second call should reuse the load balancer and only adjust the pool
+	_, err = lbs.EnsureLoadBalancer(context.Background(), "test-cluster", service, []*v1.Node{
+		testNode("node-2", 2),
+	})
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer() error = %v", err)
+	}
+	if len(mock.loadBalancers) != 1 {
+		t.Fatalf("expected 1 load balancer, got %d", len(mock.loadBalancers))
+	}
+	if !slices.Equal(lb.ServerIds, []int64{2}) {
+		t.Errorf("ServerIds = %v, want [2]", lb.ServerIds)
+	}
+}
+
+func TestEnsureLoadBalancerUnsupportedProtocol(t *testing.T) {
+	mock := &mockClient{}
+	lbs := &loadBalancers{client: mock}
+	service := testService(53)
+	service.Spec.Ports[0].Protocol = v1.ProtocolUDP
+
+	_, err := lbs.EnsureLoadBalancer(context.Background(), "test-cluster", service, nil)
+	if err == nil {
+		t.Fatal("expected error for UDP port, got nil")
+	}
+}
+
+func TestEnsureLoadBalancerUnsupportedPort(t *testing.T) {
+	mock := &mockClient{}
+	recorder := record.NewFakeRecorder(10)
+	lbs := &loadBalancers{client: mock, region: "syd", recorder: recorder}
+	service := testService(80, 8080)
+
+	_, err := lbs.EnsureLoadBalancer(context.Background(), "test-cluster", service, nil)
+	if err == nil {
+		t.Fatal("expected error for port 8080, got nil")
+	}
+	if len(mock.loadBalancers) != 0 {
+		t.Errorf("expected no load balancer to be created, got %v", mock.loadBalancers)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "UnsupportedServicePorts") || !strings.Contains(event, "8080") {
+			t.Errorf("unexpected event %q", event)
+		}
+	default:
+		t.Error("expected an UnsupportedServicePorts event")
+	}
+
+	// Setting the protocol by annotation accepts the port
+	service.Annotations = map[string]string{AnnotationLoadBalancerProtocols: "8080:http"}
+	if _, err := lbs.EnsureLoadBalancer(context.Background(), "test-cluster", service, nil); err != nil {
+		t.Fatalf("EnsureLoadBalancer() error = %v", err)
+	}
+}
+
+func TestUpdateLoadBalancer(t *testing.T) {
+	service := testService(80)
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			2: {Id: 2, Name: "node-2"},
+			3: {Id: 3, Name: "node-3"},
+		},
+		loadBalancers: map[int64]*binarylane.LoadBalancer{
+			7: {
+				Id:        7,
+				Name:      cloudprovider.DefaultLoadBalancerName(service),
+				ServerIds: []int64{1, 2},
+			},
+		},
+	}
+	lbs := &loadBalancers{client: mock}
+
+	err := lbs.UpdateLoadBalancer(context.Background(), "test-cluster", service, []*v1.Node{
+		testNode("node-2", 2),
+		testNode("node-3", 3),
+	})
+	if err != nil {
+		t.Fatalf("UpdateLoadBalancer() error = %v", err)
+	}
+
+	got := slices.Sorted(slices.Values(mock.loadBalancers[7].ServerIds))
+	if !slices.Equal(got, []int64{2, 3}) {
+		t.Errorf("ServerIds = %v, want [2 3]", got)
+	}
+}
+
+func TestEnsureLoadBalancerDeleted(t *testing.T) {
+	service := testService(80)
+	mock := &mockClient{
+		loadBalancers: map[int64]*binarylane.LoadBalancer{
+			7: {Id: 7, Name: cloudprovider.DefaultLoadBalancerName(service)},
+		},
+	}
+	lbs := &loadBalancers{client: mock}
+
+	if err := lbs.EnsureLoadBalancerDeleted(context.Background(), "test-cluster", service); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted() error = %v", err)
+	}
+	if len(mock.loadBalancers) != 0 {
+		t.Errorf("expected load balancer to be deleted, got %v", mock.loadBalancers)
+	}
+
+	// Deleting again is a no-op
+	if err := lbs.EnsureLoadBalancerDeleted(context.Background(), "test-cluster", service); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted() error = %v", err)
+	}
+}
+
+func TestGetLoadBalancer(t *testing.T) {
+	service := testService(80)
+	mock := &mockClient{}
+	lbs := &loadBalancers{client: mock}
+
+	_, exists, err := lbs.GetLoadBalancer(context.Background(), "test-cluster", service)
+	if err != nil {
+		t.Fatalf("GetLoadBalancer() error = %v", err)
+	}
+	if exists {
+		t.Error("GetLoadBalancer() exists = true, want false")
+	}
+
+	mock.loadBalancers = map[int64]*binarylane.LoadBalancer{
+		7: {Id: 7, Name: cloudprovider.DefaultLoadBalancerName(service), Ip: "203.0.113.7"},
+	}
+
+	status, exists, err := lbs.GetLoadBalancer(context.Background(), "test-cluster", service)
+	if err != nil {
+		t.Fatalf("GetLoadBalancer() error = %v", err)
+	}
+	if !exists {
+		t.Fatal("GetLoadBalancer() exists = false, want true")
+	}
+	if len(status.Ingress) != 1 || status.Ingress[0].IP != "203.0.113.7" {
+		t.Errorf("Ingress = %v, want 203.0.113.7", status.Ingress)
+	}
+}
+
+func TestGetLoadBalancerName(t *testing.T) {
+	service := testService(80)
+
+	lbs := &loadBalancers{}
+	if got := lbs.GetLoadBalancerName(context.Background(), "kubernetes", service); got != cloudprovider.DefaultLoadBalancerName(service) {
+		t.Errorf("GetLoadBalancerName() = %s, want %s", got, cloudprovider.DefaultLoadBalancerName(service))
+	}
+
+	lbs = &loadBalancers{clusterID: "prod"}
+	want := "prod-" + cloudprovider.DefaultLoadBalancerName(service)
+	if got := lbs.GetLoadBalancerName(context.Background(), "kubernetes", service); got != want {
+		t.Errorf("GetLoadBalancerName() = %s, want %s", got, want)
+	}
+}
+
+func TestParseServiceAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        *serviceAnnotations
+		wantErrs    int
+	}{
+		{
+			name: "no annotations",
+			want: &serviceAnnotations{},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				AnnotationLoadBalancerName:      "web",
+				AnnotationLoadBalancerID:        "42",
+				AnnotationLoadBalancerRegion:    "per",
+				AnnotationLoadBalancerProtocols: "80:https, metrics:http",
+				AnnotationHealthCheckPath:       "/healthz",
+				AnnotationHealthCheckProtocol:   "both",
+
+				AnnotationLoadBalancerRecreateErrored: "true",
+			},
+			want: &serviceAnnotations{
+				name:           "web",
+				loadBalancerID: 42,
+				region:         "per",
+				protocols: map[int32]binarylane.LoadBalancerRuleProtocol{
+					80:   binarylane.LoadBalancerRuleProtocolHttps,
+					9090: binarylane.LoadBalancerRuleProtocolHttp,
+				},
+				healthCheck: &binarylane.HealthCheckRequest{
+					Path:     toPtr("/healthz"),
+					Protocol: toPtr(binarylane.HealthCheckProtocolBoth),
+				},
+				recreateErrored: true,
+			},
+		},
+		{
+			name:        "anycast",
+			annotations: map[string]string{AnnotationLoadBalancerRegion: "anycast"},
+			want:        &serviceAnnotations{anycast: true},
+		},
+		{
+			name:        "health check protocol only",
+			annotations: map[string]string{AnnotationHealthCheckProtocol: "https"},
+			want: &serviceAnnotations{
+				healthCheck: &binarylane.HealthCheckRequest{Protocol: toPtr(binarylane.HealthCheckProtocolHttps)},
+			},
+		},
+		{
+			name: "invalid values",
+			annotations: map[string]string{
+				AnnotationLoadBalancerName:    "Web_LB",
+				AnnotationLoadBalancerID:      "abc",
+				AnnotationLoadBalancerRegion:  "Sydney!",
+				AnnotationHealthCheckPath:     "healthz",
+				AnnotationHealthCheckProtocol: "tcp",
+
+				AnnotationLoadBalancerRecreateErrored: "yes please",
+			},
+			want:     &serviceAnnotations{},
+			wantErrs: 6,
+		},
+		{
+			name:        "unknown port",
+			annotations: map[string]string{AnnotationLoadBalancerProtocols: "8443:https"},
+			want:        &serviceAnnotations{},
+			wantErrs:    1,
+		},
+		{
+			name:        "unsupported protocol",
+			annotations: map[string]string{AnnotationLoadBalancerProtocols: "80:tcp"},
+			want:        &serviceAnnotations{},
+			wantErrs:    1,
+		},
+		{
+			name:        "duplicate port",
+			annotations: map[string]string{AnnotationLoadBalancerProtocols: "80:http,80:https"},
+			want:        &serviceAnnotations{},
+			wantErrs:    1,
+		},
+		{
+			name:        "missing protocol",
+			annotations: map[string]string{AnnotationLoadBalancerProtocols: "80"},
+			want:        &serviceAnnotations{},
+			wantErrs:    1,
+		},
+		{
+			name:        "invalid node selector",
+			annotations: map[string]string{AnnotationLoadBalancerNodeSelector: "pool in (web"},
+			want:        &serviceAnnotations{},
+			wantErrs:    1,
+		},
+		{
+			name:        "negative load balancer ID",
+			annotations: map[string]string{AnnotationLoadBalancerID: "-1"},
+			want:        &serviceAnnotations{},
+			wantErrs:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(80, 9090)
+			service.Spec.Ports[1].Name = "metrics"
+			service.Annotations = tt.annotations
+
+			got, errs := parseServiceAnnotations(service)
+			if len(errs) != tt.wantErrs {
+				t.Fatalf("parseServiceAnnotations() errors = %v, want %d", errs, tt.wantErrs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseServiceAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureLoadBalancerAnnotations(t *testing.T) {
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {Id: 1, Name: "node-1", Region: binarylane.Region{Slug: "syd"}},
+		},
+	}
+	lbs := &loadBalancers{client: mock, clusterID: "prod"}
+	service := testService(80, 8443)
+	service.Annotations = map[string]string{
+		AnnotationLoadBalancerName:      "web",
+		AnnotationLoadBalancerRegion:    "anycast",
+		AnnotationLoadBalancerProtocols: "8443:https",
+		AnnotationHealthCheckPath:       "/healthz",
+	}
+	nodes := []*v1.Node{testNode("node-1", 1)}
+
+	if _, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer() error = %v", err)
+	}
+	lb := mock.loadBalancers[1]
+	if lb.Name != "prod-web" {
+		t.Errorf("Name = %s, want prod-web", lb.Name)
+	}
+	if lb.Region != nil {
+		t.Errorf("Region = %v, want nil for anycast", lb.Region)
+	}
+	wantRules := []binarylane.ForwardingRule{
+		{EntryProtocol: binarylane.LoadBalancerRuleProtocolHttp},
+		{EntryProtocol: binarylane.LoadBalancerRuleProtocolHttps},
+	}
+	if !slices.Equal(lb.ForwardingRules, wantRules) {
+		t.Errorf("ForwardingRules = %v, want %v", lb.ForwardingRules, wantRules)
+	}
+	if lb.HealthCheck.Path != "/healthz" {
+		t.Errorf("HealthCheck.Path = %s, want /healthz", lb.HealthCheck.Path)
+	}
+	if got := lbs.GetLoadBalancerName(context.Background(), "kubernetes", service); got != "prod-web" {
+		t.Errorf("GetLoadBalancerName() = %s, want prod-web", got)
+	}
+
+	// Changing the health check updates the existing load balancer
+	service.Annotations[AnnotationHealthCheckProtocol] = "https"
+	if _, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer() error = %v", err)
+	}
+	if len(mock.loadBalancers) != 1 {
+		t.Fatalf("expected 1 load balancer, got %d", len(mock.loadBalancers))
+	}
+	if lb.HealthCheck.Protocol != binarylane.HealthCheckProtocolHttps {
+		t.Errorf("HealthCheck.Protocol = %s, want https", lb.HealthCheck.Protocol)
+	}
+}
+
+func TestEnsureLoadBalancerAdopt(t *testing.T) {
+	service := testService(80)
+	service.Annotations = map[string]string{AnnotationLoadBalancerID: "7"}
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			2: {Id: 2, Name: "node-2"},
+		},
+		loadBalancers: map[int64]*binarylane.LoadBalancer{
+			7: {
+				Id:              7,
+				Name:            "made-by-hand",
+				Ip:              "203.0.113.7",
+				ForwardingRules: []binarylane.ForwardingRule{{EntryProtocol: binarylane.LoadBalancerRuleProtocolHttp}},
+				ServerIds:       []int64{1},
+			},
+		},
+	}
+	lbs := &loadBalancers{client: mock, clusterID: "prod"}
+
+	status, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, []*v1.Node{testNode("node-2", 2)})
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer() error = %v", err)
+	}
+	if len(mock.loadBalancers) != 1 {
+		t.Fatalf("expected the existing load balancer to be adopted, got %v", mock.loadBalancers)
+	}
+	lb := mock.loadBalancers[7]
+	if want := "prod-" + cloudprovider.DefaultLoadBalancerName(service); lb.Name != want {
+		t.Errorf("Name = %s, want %s", lb.Name, want)
+	}
+	if !slices.Equal(lb.ServerIds, []int64{2}) {
+		t.Errorf("ServerIds = %v, want [2]", lb.ServerIds)
+	}
+	if len(status.Ingress) != 1 || status.Ingress[0].IP != "203.0.113.7" {
+		t.Errorf("Ingress = %v, want 203.0.113.7", status.Ingress)
+	}
+
+	if err := lbs.EnsureLoadBalancerDeleted(context.Background(), "kubernetes", service); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted() error = %v", err)
+	}
+	if len(mock.loadBalancers) != 0 {
+		t.Errorf("expected adopted load balancer to be deleted, got %v", mock.loadBalancers)
+	}
+
+	// A missing load balancer is not replaced by a new one
+	if _, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, nil); !errors.Is(err, binarylane.ErrLoadBalancerNotFound) {
+		t.Errorf("EnsureLoadBalancer() error = %v, want ErrLoadBalancerNotFound", err)
+	}
+	if len(mock.loadBalancers) != 0 {
+		t.Errorf("expected no load balancer to be created, got %v", mock.loadBalancers)
+	}
+}
+
+func TestEnsureLoadBalancerRenamed(t *testing.T) {
+	service := testService(80)
+	service.Annotations = map[string]string{AnnotationLoadBalancerName: "web"}
+	service.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "203.0.113.7"}}
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {Id: 1, Name: "node-1"},
+		},
+		loadBalancers: map[int64]*binarylane.LoadBalancer{
+			6: {
+				Id:   6,
+				Name: "staging-old-name",
+				Ip:   "203.0.113.7",
+			},
+			7: {
+				Id:              7,
+				Name:            "prod-old-name",
+				Ip:              "203.0.113.7",
+				ForwardingRules: []binarylane.ForwardingRule{{EntryProtocol: binarylane.LoadBalancerRuleProtocolHttp}},
+				ServerIds:       []int64{1},
+			},
+		},
+	}
+	lbs := &loadBalancers{client: mock, clusterID: "prod"}
+
+	status, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, []*v1.Node{testNode("node-1", 1)})
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer() error = %v", err)
+	}
+	if len(mock.loadBalancers) != 2 {
+		t.Fatalf("expected the load balancer to be renamed, not created, got %v", mock.loadBalancers)
+	}
+	if lb := mock.loadBalancers[7]; lb.Name != "prod-web" {
+		t.Errorf("Name = %s, want prod-web", lb.Name)
+	}
+	if lb := mock.loadBalancers[6]; lb.Name != "staging-old-name" {
+		t.Errorf("load balancer of another cluster was renamed to %s", lb.Name)
+	}
+	if len(status.Ingress) != 1 || status.Ingress[0].IP != "203.0.113.7" {
+		t.Errorf("Ingress = %v, want 203.0.113.7", status.Ingress)
+	}
+}
+
+func TestEnsureLoadBalancerInvalidAnnotations(t *testing.T) {
+	mock := &mockClient{}
+	recorder := record.NewFakeRecorder(10)
+	lbs := &loadBalancers{client: mock, region: "syd", recorder: recorder}
+	service := testService(80)
+	service.Annotations = map[string]string{
+		AnnotationLoadBalancerRegion:  "Sydney!",
+		AnnotationHealthCheckProtocol: "tcp",
+	}
+
+	if _, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, nil); err == nil {
+		t.Fatal("expected error for invalid annotations, got nil")
+	}
+	if len(mock.loadBalancers) != 0 {
+		t.Errorf("expected no load balancer to be created, got %v", mock.loadBalancers)
+	}
+
+	for _, annotation := range []string{AnnotationLoadBalancerRegion, AnnotationHealthCheckProtocol} {
+		select {
+		case event := <-recorder.Events:
+			if !strings.Contains(event, "InvalidAnnotation") || !strings.Contains(event, annotation) {
+				t.Errorf("unexpected event %q, want InvalidAnnotation for %s", event, annotation)
+			}
+		default:
+			t.Errorf("expected an InvalidAnnotation event for %s", annotation)
+		}
+	}
+}
+
+func TestEnsureLoadBalancerNameTooLong(t *testing.T) {
+	mock := &mockClient{}
+	recorder := record.NewFakeRecorder(10)
+	lbs := &loadBalancers{client: mock, region: "syd", clusterID: "production-cluster", recorder: recorder}
+	service := testService(80)
+	// A valid DNS label on its own, but not once prefixed with the cluster ID
+	service.Annotations = map[string]string{AnnotationLoadBalancerName: strings.Repeat("a", 50)}
+
+	if _, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, nil); err == nil {
+		t.Fatal("expected error for a name that is too long, got nil")
+	}
+	if len(mock.loadBalancers) != 0 {
+		t.Errorf("expected no load balancer to be created, got %v", mock.loadBalancers)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "InvalidAnnotation") || !strings.Contains(event, AnnotationLoadBalancerName) {
+			t.Errorf("unexpected event %q, want InvalidAnnotation for %s", event, AnnotationLoadBalancerName)
+		}
+	default:
+		t.Errorf("expected an InvalidAnnotation event for %s", AnnotationLoadBalancerName)
+	}
+}
+
+func TestEnsureLoadBalancerDeletedInvalidAnnotations(t *testing.T) {
+	service := testService(80)
+	service.Annotations = map[string]string{
+		AnnotationLoadBalancerName: "web",
+		AnnotationLoadBalancerID:   "seven",
+	}
+	mock := &mockClient{
+		loadBalancers: map[int64]*binarylane.LoadBalancer{
+			7: {Id: 7, Name: "prod-" + cloudprovider.DefaultLoadBalancerName(service)},
+		},
+	}
+	lbs := &loadBalancers{client: mock, clusterID: "prod"}
+
+	// The load balancer with the default name may not be the service's
+	if err := lbs.EnsureLoadBalancerDeleted(context.Background(), "kubernetes", service); err == nil {
+		t.Fatal("expected error for invalid annotations, got nil")
+	}
+	if len(mock.loadBalancers) != 1 {
+		t.Errorf("expected no load balancer to be deleted, got %v", mock.loadBalancers)
+	}
+}
+
+func TestEnsureLoadBalancerAvailability(t *testing.T) {
+	regional := binarylane.LoadBalancerAvailabilityOption{Regions: &[]string{"syd", "mel"}, PriceHourly: 0.015, PriceMonthly: 10}
+	anycast := binarylane.LoadBalancerAvailabilityOption{Anycast: true, PriceHourly: 0.0375, PriceMonthly: 25}
+
+	tests := []struct {
+		name         string
+		anycast      bool
+		annotations  map[string]string
+		availability []binarylane.LoadBalancerAvailabilityOption
+		wantRegion   *string
+		wantPrices   []string
+		wantEvent    string
+	}{
+		{
+			name:         "region",
+			availability: []binarylane.LoadBalancerAvailabilityOption{regional, anycast},
+			wantRegion:   toPtr("syd"),
+			wantPrices:   []string{"0.015", "10"},
+		},
+		{
+			name:         "anycast mode",
+			anycast:      true,
+			availability: []binarylane.LoadBalancerAvailabilityOption{regional, anycast},
+			wantPrices:   []string{"0.0375", "25"},
+		},
+		{
+			name:         "region annotation overrides anycast mode",
+			anycast:      true,
+			annotations:  map[string]string{AnnotationLoadBalancerRegion: "mel"},
+			availability: []binarylane.LoadBalancerAvailabilityOption{regional, anycast},
+			wantRegion:   toPtr("mel"),
+			wantPrices:   []string{"0.015", "10"},
+		},
+		{
+			name:         "region unavailable",
+			annotations:  map[string]string{AnnotationLoadBalancerRegion: "per"},
+			availability: []binarylane.LoadBalancerAvailabilityOption{regional, anycast},
+			wantEvent:    "available regions: mel, syd",
+		},
+		{
+			name:         "anycast unavailable",
+			annotations:  map[string]string{AnnotationLoadBalancerRegion: "anycast"},
+			availability: []binarylane.LoadBalancerAvailabilityOption{regional},
+			wantEvent:    "anycast load balancers are not available",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{
+				servers: map[int64]*binarylane.Server{
+					1: {Id: 1, Name: "node-1", Region: binarylane.Region{Slug: "syd"}},
+				},
+				availability: tt.availability,
+			}
+			service := testService(80)
+			service.Annotations = tt.annotations
+			kubeClient := fake.NewClientset(service)
+			recorder := record.NewFakeRecorder(10)
+			lbs := &loadBalancers{client: mock, anycast: tt.anycast, recorder: recorder, kubeClient: kubeClient}
+
+			_, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, []*v1.Node{testNode("node-1", 1)})
+
+			if tt.wantEvent != "" {
+				if err == nil {
+					t.Fatal("expected error for unavailable load balancer, got nil")
+				}
+				if len(mock.loadBalancers) != 0 {
+					t.Errorf("expected no load balancer to be created, got %v", mock.loadBalancers)
+				}
+				select {
+				case event := <-recorder.Events:
+					if !strings.Contains(event, "LoadBalancerUnavailable") || !strings.Contains(event, tt.wantEvent) {
+						t.Errorf("unexpected event %q, want LoadBalancerUnavailable containing %q", event, tt.wantEvent)
+					}
+				default:
+					t.Error("expected a LoadBalancerUnavailable event")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("EnsureLoadBalancer() error = %v", err)
+			}
+			lb := mock.loadBalancers[1]
+			if tt.wantRegion == nil && lb.Region != nil || tt.wantRegion != nil && (lb.Region == nil || lb.Region.Slug != *tt.wantRegion) {
+				t.Errorf("Region = %v, want %v", lb.Region, tt.wantRegion)
+			}
+
+			got, err := kubeClient.CoreV1().Services(service.Namespace).Get(context.Background(), service.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get service: %v", err)
+			}
+			prices := []string{got.Annotations[AnnotationLoadBalancerPriceHourly], got.Annotations[AnnotationLoadBalancerPriceMonthly]}
+			if !slices.Equal(prices, tt.wantPrices) {
+				t.Errorf("price annotations = %v, want %v", prices, tt.wantPrices)
+			}
+		})
+	}
+}
+
+func TestEnsureLoadBalancerLocalTrafficPolicy(t *testing.T) {
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {Id: 1, Name: "node-1", Region: binarylane.Region{Slug: "syd"}},
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	lbs := &loadBalancers{client: mock, recorder: recorder, trafficPolicies: &sync.Map{}}
+	service := testService(80)
+	service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyLocal
+	service.Spec.HealthCheckNodePort = 30123
+	nodes := []*v1.Node{testNode("node-1", 1)}
+
+	ensure := func(wantEvent bool) {
+		t.Helper()
+		if _, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, nodes); err != nil {
+			t.Fatalf("EnsureLoadBalancer() error = %v", err)
+		}
+		select {
+		case event := <-recorder.Events:
+			if !wantEvent {
+				t.Errorf("unexpected event %q", event)
+			} else if !strings.Contains(event, "HealthCheckNodePortUnsupported") || !strings.Contains(event, "30123") {
+				t.Errorf("unexpected event %q", event)
+			}
+		default:
+			if wantEvent {
+				t.Error("expected a HealthCheckNodePortUnsupported event")
+			}
+		}
+	}
+
+	// Without an annotation the default check is kept, with a warning when
+	// the load balancer is created
+	ensure(true)
+	lb := mock.loadBalancers[1]
+	if want := mockHealthCheck(nil); lb.HealthCheck != want {
+		t.Errorf("HealthCheck = %+v, want the default %+v", lb.HealthCheck, want)
+	}
+
+	// Resyncs do not repeat the warning
+	ensure(false)
+
+	// Changing the policy back to Local warns again
+	service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyCluster
+	ensure(false)
+	service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyLocal
+	ensure(true)
+
+	// Opting in to /healthz through the annotation sets the check, without a warning
+	service.Annotations = map[string]string{AnnotationHealthCheckPath: "/healthz"}
+	service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyCluster
+	ensure(false)
+	service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyLocal
+	ensure(false)
+	if lb.HealthCheck.Path != "/healthz" {
+		t.Errorf("HealthCheck.Path = %s, want /healthz", lb.HealthCheck.Path)
+	}
+}
+
+func TestBackendServerIDs(t *testing.T) {
+	node := func(name string, serverID int64, modify func(*v1.Node)) *v1.Node {
+		n := testNode(name, serverID)
+		n.Labels = map[string]string{"pool": "web"}
+		if modify != nil {
+			modify(n)
+		}
+		return n
+	}
+	nodes := []*v1.Node{
+		node("ready", 1, nil),
+		node("excluded", 2, func(n *v1.Node) {
+			n.Labels[v1.LabelNodeExcludeBalancers] = ""
+		}),
+		node("control-plane", 3, func(n *v1.Node) {
+			n.Labels[controlPlaneNodeLabel] = ""
+		}),
+		node("batch", 4, func(n *v1.Node) {
+			n.Labels["pool"] = "batch"
+		}),
+		node("not-ready", 5, func(n *v1.Node) {
+			n.Status.Conditions[0].Status = v1.ConditionFalse
+		}),
+		node("no-conditions", 6, func(n *v1.Node) {
+			n.Status.Conditions = nil
+		}),
+		node("shutdown", 7, func(n *v1.Node) {
+			n.Spec.Taints = []v1.Taint{{Key: cloudproviderapi.TaintNodeShutdown, Effect: v1.TaintEffectNoSchedule}}
+		}),
+		node("uninitialized", 0, func(n *v1.Node) {
+			n.Spec.ProviderID = ""
+		}),
+		// Powered off, but not tainted by the node lifecycle controller yet
+		node("powered-off", 8, nil),
+		node("deleted", 9, nil),
+	}
+	mock := &mockClient{servers: map[int64]*binarylane.Server{}}
+	for id := range int64(9) {
+		mock.servers[id] = &binarylane.Server{Id: id, Status: "active"}
+	}
+	mock.servers[8].Status = "off"
+
+	tests := []struct {
+		name                string
+		excludeControlPlane bool
+		selector            string
+		want                []int64
+	}{
+		{
+			name: "default",
+			want: []int64{1, 3, 4},
+		},
+		{
+			name:                "exclude control plane",
+			excludeControlPlane: true,
+			want:                []int64{1, 4},
+		},
+		{
+			name:     "node selector",
+			selector: "pool=web",
+			want:     []int64{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(80)
+			if tt.selector != "" {
+				service.Annotations = map[string]string{AnnotationLoadBalancerNodeSelector: tt.selector}
+			}
+			annotations, errs := parseServiceAnnotations(service)
+			if len(errs) > 0 {
+				t.Fatalf("parseServiceAnnotations() errors = %v", errs)
+			}
+			lbs := &loadBalancers{client: mock, excludeControlPlane: tt.excludeControlPlane}
+
+			got, err := lbs.backendServerIDs(context.Background(), service, annotations, nodes)
+			if err != nil {
+				t.Fatalf("backendServerIDs() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("backendServerIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateLoadBalancerInvalidNodeSelector(t *testing.T) {
+	service := testService(80)
+	service.Annotations = map[string]string{AnnotationLoadBalancerNodeSelector: "pool in (web"}
+	mock := &mockClient{
+		loadBalancers: map[int64]*binarylane.LoadBalancer{
+			7: {Id: 7, Name: cloudprovider.DefaultLoadBalancerName(service), ServerIds: []int64{1}},
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	lbs := &loadBalancers{client: mock, recorder: recorder}
+
+	err := lbs.UpdateLoadBalancer(context.Background(), "kubernetes", service, []*v1.Node{
+		testNode("node-1", 1),
+		testNode("node-2", 2),
+	})
+	if err == nil {
+		t.Fatal("expected error for invalid node selector, got nil")
+	}
+	if !slices.Equal(mock.loadBalancers[7].ServerIds, []int64{1}) {
+		t.Errorf("ServerIds = %v, want the pool unchanged", mock.loadBalancers[7].ServerIds)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "InvalidAnnotation") {
+			t.Errorf("unexpected event %q", event)
+		}
+	default:
+		t.Error("expected an InvalidAnnotation event")
+	}
+}
+
+// provisioningClient reports a load balancer as new until it has been polled
+// activeAfter times.
+type provisioningClient struct {
+	*mockClient
+	activeAfter int
+	polls       int
+}
+
+func (p *provisioningClient) GetLoadBalancer(ctx context.Context, loadBalancerID int64) (*binarylane.LoadBalancer, error) {
+	lb, err := p.mockClient.GetLoadBalancer(ctx, loadBalancerID)
+	if err != nil {
+		return nil, err
+	}
+	p.polls++
+	if p.activeAfter > 0 && p.polls >= p.activeAfter {
+		lb.Status = binarylane.LoadBalancerStatusActive
+	}
+	return lb, nil
+}
+
+func TestEnsureLoadBalancerWaitsForActive(t *testing.T) {
+	tests := []struct {
+		name        string
+		activeAfter int
+		wantRetry   bool
+	}{
+		{name: "becomes active", activeAfter: 2},
+		{name: "still provisioning", wantRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(80)
+			client := &provisioningClient{
+				mockClient: &mockClient{
+					loadBalancers: map[int64]*binarylane.LoadBalancer{
+						7: {
+							Id:              7,
+							Name:            cloudprovider.DefaultLoadBalancerName(service),
+							Ip:              "203.0.113.7",
+							Status:          binarylane.LoadBalancerStatusNew,
+							ForwardingRules: []binarylane.ForwardingRule{{EntryProtocol: binarylane.LoadBalancerRuleProtocolHttp}},
+						},
+					},
+				},
+				activeAfter: tt.activeAfter,
+			}
+			recorder := record.NewFakeRecorder(10)
+			lbs := &loadBalancers{
+				client:       client,
+				recorder:     recorder,
+				waitInterval: time.Millisecond,
+				waitTimeout:  50 * time.Millisecond,
+			}
+
+			status, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, nil)
+
+			var retryErr *cloudproviderapi.RetryError
+			if tt.wantRetry {
+				if !errors.As(err, &retryErr) {
+					t.Fatalf("EnsureLoadBalancer() error = %v, want a retry error", err)
+				}
+				if retryErr.RetryAfter() != loadBalancerRetryAfter {
+					t.Errorf("RetryAfter() = %v, want %v", retryErr.RetryAfter(), loadBalancerRetryAfter)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("EnsureLoadBalancer() error = %v", err)
+				}
+				if len(status.Ingress) != 1 || status.Ingress[0].IP != "203.0.113.7" {
+					t.Errorf("Ingress = %v, want 203.0.113.7", status.Ingress)
+				}
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if !strings.Contains(event, "WaitingForLoadBalancer") {
+					t.Errorf("unexpected event %q", event)
+				}
+			default:
+				t.Error("expected a WaitingForLoadBalancer event")
+			}
+		})
+	}
+}
+
+func TestEnsureLoadBalancerErrored(t *testing.T) {
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		wantErr      bool
+		wantEvents   []string
+		wantRecreate bool
+	}{
+		{
+			name:       "reported",
+			wantErr:    true,
+			wantEvents: []string{"LoadBalancerErrored"},
+		},
+		{
+			name:         "recreated",
+			annotations:  map[string]string{AnnotationLoadBalancerRecreateErrored: "true"},
+			wantEvents:   []string{"RecreatingLoadBalancer"},
+			wantRecreate: true,
+		},
+		{
+			name: "adopted",
+			annotations: map[string]string{
+				AnnotationLoadBalancerRecreateErrored: "true",
+				AnnotationLoadBalancerID:              "7",
+			},
+			wantErr:    true,
+			wantEvents: []string{"LoadBalancerErrored"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(80)
+			service.Annotations = tt.annotations
+			mock := &mockClient{
+				servers: map[int64]*binarylane.Server{
+					1: {Id: 1, Name: "node-1", Region: binarylane.Region{Slug: "syd"}},
+				},
+				loadBalancers: map[int64]*binarylane.LoadBalancer{
+					7: {
+						Id:     7,
+						Name:   cloudprovider.DefaultLoadBalancerName(service),
+						Status: binarylane.LoadBalancerStatusErrored,
+					},
+				},
+			}
+			recorder := record.NewFakeRecorder(10)
+			lbs := &loadBalancers{client: mock, recorder: recorder}
+
+			_, err := lbs.EnsureLoadBalancer(context.Background(), "kubernetes", service, []*v1.Node{testNode("node-1", 1)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureLoadBalancer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			_, errored := mock.loadBalancers[7]
+			if errored == tt.wantRecreate {
+				t.Errorf("errored load balancer exists = %v, want %v", errored, !tt.wantRecreate)
+			}
+			if tt.wantRecreate && len(mock.loadBalancers) != 1 {
+				t.Errorf("expected a new load balancer, got %v", mock.loadBalancers)
+			}
+
+			for _, reason := range tt.wantEvents {
+				select {
+				case event := <-recorder.Events:
+					if !strings.Contains(event, reason) || !strings.Contains(event, "(7)") {
+						t.Errorf("unexpected event %q, want %s with the load balancer ID", event, reason)
+					}
+				default:
+					t.Errorf("expected a %s event", reason)
+				}
+			}
+		})
+	}
+}
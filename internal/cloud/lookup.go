@@ -0,0 +1,147 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// findServer walks the lookup chain until a method matches the node. Methods
+// that do not apply, such as providerID for a node that has none yet, are
+// skipped. A method matching more than one server is an error rather than a
+// guess.
+func findServer(ctx context.Context, client cloudClientInterface, methods []NodeLookupMethod, node *v1.Node) (*binarylane.Server, error) {
+	if len(methods) == 0 {
+		methods = defaultNodeLookup
+	}
+
+	var servers []binarylane.Server
+	for _, method := range methods {
+		switch method {
+		case NodeLookupProviderID:
+			if node.Spec.ProviderID == "" {
+				continue
+			}
+			id, err := parseProviderID(node.Spec.ProviderID)
+			if err != nil {
+				klog.Warningf("Ignoring provider ID of node %s: %v", node.Name, err)
+				continue
+			}
+			return client.GetServer(ctx, id)
+
+		case NodeLookupHostname:
+			server, err := client.GetServerByName(ctx, node.Name)
+			if errors.Is(err, binarylane.ErrServerNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to find server for node %s by %s: %w", node.Name, method, err)
+			}
+			return server, nil
+
+		default:
+			if servers == nil {
+				var err error
+				if servers, err = client.ListServers(ctx); err != nil {
+					return nil, fmt.Errorf("failed to list servers: %w", err)
+				}
+			}
+
+			var matches []binarylane.Server
+			for _, server := range servers {
+				if serverMatches(method, &server, node) {
+					matches = append(matches, server)
+				}
+			}
+			switch len(matches) {
+			case 0:
+				continue
+			case 1:
+				return &matches[0], nil
+			default:
+				return nil, fmt.Errorf("node %s by %s: %w: %s", node.Name, method, binarylane.ErrMultipleServers, describeServers(matches))
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no server matches node %s", binarylane.ErrServerNotFound, node.Name)
+}
+
+// findServerByNodeName is findServer for callers that only know the node
+// name, such as the routes controller.
+func findServerByNodeName(ctx context.Context, client cloudClientInterface, methods []NodeLookupMethod, nodeName string) (*binarylane.Server, error) {
+	node := &v1.Node{}
+	node.Name = nodeName
+	return findServer(ctx, client, methods, node)
+}
+
+func serverMatches(method NodeLookupMethod, server *binarylane.Server, node *v1.Node) bool {
+	switch method {
+	case NodeLookupShortHostname:
+		return strings.EqualFold(shortName(server.Name), shortName(node.Name))
+
+	case NodeLookupInternalIP:
+		for _, address := range node.Status.Addresses {
+			if address.Type != v1.NodeInternalIP {
+				continue
+			}
+			if slices.Contains(privateIPs(server), address.Address) {
+				return true
+			}
+		}
+
+	case NodeLookupPermalink:
+		return server.Permalink != nil && *server.Permalink == node.Name
+	}
+
+	return false
+}
+
+func shortName(name string) string {
+	name, _, _ = strings.Cut(name, ".")
+	return name
+}
+
+// describeServers formats servers as "id (name)" for error messages.
+func describeServers(servers []binarylane.Server) string {
+	descriptions := make([]string, len(servers))
+	for i, server := range servers {
+		descriptions[i] = fmt.Sprintf("%d (%s)", server.Id, server.Name)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// privateIPs returns the server's VPC addresses, IPv4 first.
+func privateIPs(server *binarylane.Server) []string {
+	var ips []string
+	for _, networks := range [][]binarylane.Network{server.Networks.V4, server.Networks.V6} {
+		for _, net := range networks {
+			if net.Type == "private" {
+				ips = append(ips, net.IpAddress)
+			}
+		}
+	}
+	return ips
+}
+
+// privateIPFor returns the server's VPC address in the same family as prefix,
+// or an empty string when it has none.
+func privateIPFor(server *binarylane.Server, prefix netip.Prefix) string {
+	networks := server.Networks.V4
+	if prefix.Addr().Is6() {
+		networks = server.Networks.V6
+	}
+	for _, net := range networks {
+		if net.Type == "private" {
+			return net.IpAddress
+		}
+	}
+	return ""
+}
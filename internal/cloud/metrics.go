@@ -0,0 +1,83 @@
+package cloud
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/version"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	operationsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "binarylane_ccm",
+			Subsystem:      "cloudprovider",
+			Name:           "operations_total",
+			Help:           "Number of cloud provider operations by operation and result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation", "result"},
+	)
+
+	operationDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "binarylane_ccm",
+			Subsystem:      "cloudprovider",
+			Name:           "operation_duration_seconds",
+			Help:           "Latency of cloud provider operations by operation and result.",
+			Buckets:        metrics.ExponentialBuckets(0.05, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation", "result"},
+	)
+
+	blackholeRoutes = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      "binarylane_ccm",
+			Subsystem:      "cloudprovider",
+			Name:           "blackhole_routes",
+			Help:           "Number of route entries pointing at a private IP that no longer belongs to a server, as of the last route sync.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	buildInfo = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "binarylane_ccm",
+			Name:           "build_info",
+			Help:           "Build information of the running cloud controller manager, always 1.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"version", "git_commit", "build_date", "go_version"},
+	)
+
+	registerMetricsOnce sync.Once
+)
+
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		binarylane.RegisterMetrics()
+		legacyregistry.MustRegister(operationsTotal)
+		legacyregistry.MustRegister(operationDuration)
+		legacyregistry.MustRegister(blackholeRoutes)
+		legacyregistry.MustRegister(buildInfo)
+
+		info := version.Get()
+		buildInfo.WithLabelValues(info.Version, info.GitCommit, info.BuildDate, info.GoVersion).Set(1)
+	})
+}
+
+// recordOperation records the outcome of a cloud provider operation. It is
+// deferred at the start of the operation with a pointer to its named error
+// result.
+func recordOperation(operation string, start time.Time, err *error) {
+	result := "success"
+	if *err != nil {
+		result = "error"
+	}
+	operationsTotal.WithLabelValues(operation, result).Inc()
+	operationDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,64 @@
+package cloud
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// ownershipMarker starts the description of every route entry managed by
+	// this cloud controller manager.
+	ownershipMarker = "k8s-ccm"
+
+	// maxClusterIDLength leaves room for the default load balancer name, which
+	// is 32 characters, in a 63 character hostname.
+	maxClusterIDLength = 30
+)
+
+// validateClusterID checks that the cluster ID can be embedded in load
+// balancer hostnames and route descriptions.
+func validateClusterID(clusterID string) error {
+	if errs := validation.IsDNS1123Label(clusterID); len(errs) > 0 {
+		return fmt.Errorf("cluster ID %q is invalid: %s", clusterID, strings.Join(errs, ", "))
+	}
+	if len(clusterID) > maxClusterIDLength {
+		return fmt.Errorf("cluster ID %q must be no more than %d characters", clusterID, maxClusterIDLength)
+	}
+	return nil
+}
+
+// routeDescription returns the route entry description that marks an entry as
+// owned by the given cluster, e.g. "k8s-ccm cluster=prod node=worker-1".
+func routeDescription(clusterID, nodeName string) string {
+	return fmt.Sprintf("%s cluster=%s node=%s", ownershipMarker, clusterID, nodeName)
+}
+
+// parseRouteDescription extracts the owning cluster and node from a route
+// entry description. ok is false for entries without an ownership marker.
+func parseRouteDescription(description *string) (clusterID, nodeName string, ok bool) {
+	if description == nil {
+		return "", "", false
+	}
+
+	fields := strings.Fields(*description)
+	if len(fields) == 0 || fields[0] != ownershipMarker {
+		return "", "", false
+	}
+
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "cluster":
+			clusterID = value
+		case "node":
+			nodeName = value
+		}
+	}
+
+	return clusterID, nodeName, clusterID != ""
+}
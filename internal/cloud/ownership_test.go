@@ -0,0 +1,62 @@
+package cloud
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRouteDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		description *string
+		wantCluster string
+		wantNode    string
+		wantOK      bool
+	}{
+		{
+			name:        "owned route",
+			description: toPtr(routeDescription("prod", "worker-1")),
+			wantCluster: "prod",
+			wantNode:    "worker-1",
+			wantOK:      true,
+		},
+		{
+			name:        "nil description",
+			description: nil,
+		},
+		{
+			name:        "manual route",
+			description: toPtr("VPN gateway"),
+		},
+		{
+			name:        "legacy route",
+			description: toPtr("Kubernetes route for node worker-1"),
+		},
+		{
+			name:        "marker without cluster",
+			description: toPtr("k8s-ccm node=worker-1"),
+			wantNode:    "worker-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, node, ok := parseRouteDescription(tt.description)
+			if cluster != tt.wantCluster || node != tt.wantNode || ok != tt.wantOK {
+				t.Errorf("parseRouteDescription() = (%q, %q, %v), want (%q, %q, %v)", cluster, node, ok, tt.wantCluster, tt.wantNode, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateClusterID(t *testing.T) {
+	if err := validateClusterID("prod-2"); err != nil {
+		t.Errorf("validateClusterID() error = %v", err)
+	}
+	if err := validateClusterID("Prod_2"); err == nil {
+		t.Error("validateClusterID() expected error for invalid characters")
+	}
+	if err := validateClusterID(strings.Repeat("a", maxClusterIDLength+1)); err == nil {
+		t.Error("validateClusterID() expected error for long cluster ID")
+	}
+}
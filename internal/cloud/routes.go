@@ -4,21 +4,110 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
 )
 
 var _ cloudprovider.Routes = &routes{}
 
+const actionTimeout = 2 * time.Minute
+
 type routes struct {
-	client cloudClientInterface
-	cidr   string
+	client     cloudClientInterface
+	cidr       string
+	vpcID      int64
+	clusterID  string
+	nodeLookup []NodeLookupMethod
+	// adoptLegacyEntries treats unmarked entries that look like they were
+	// created by an older version of this cloud controller manager as owned.
+	adoptLegacyEntries bool
+	// keepSourceDestCheck leaves the source and destination check of target
+	// servers enabled, for clusters that manage it themselves.
+	keepSourceDestCheck bool
+	// recorder reports blackholed routes, and may be nil.
+	recorder record.EventRecorder
+
+	// updater serialises route table writes. It is shared by every routes
+	// instance of a cloud, and created on first use when unset.
+	updater     *vpcRouteUpdater
+	updaterOnce sync.Once
+	// blackholes tracks reported blackholed routes. It is shared by every
+	// routes instance of a cloud, and created on first use when unset.
+	blackholes     *blackholeReporter
+	blackholesOnce sync.Once
+}
+
+func (r *routes) vpcUpdater() *vpcRouteUpdater {
+	r.updaterOnce.Do(func() {
+		if r.updater == nil {
+			r.updater = newVpcRouteUpdater(r.client)
+		}
+	})
+	return r.updater
+}
+
+func (r *routes) blackholeReporter() *blackholeReporter {
+	r.blackholesOnce.Do(func() {
+		if r.blackholes == nil {
+			r.blackholes = newBlackholeReporter()
+		}
+	})
+	return r.blackholes
+}
+
+// owner returns the cluster ID stamped into route entry descriptions, falling
+// back to the cluster name when no cluster ID is configured.
+func (r *routes) owner(clusterName string) string {
+	if r.clusterID != "" {
+		return r.clusterID
+	}
+	return clusterName
+}
+
+// ownsEntry reports whether a route entry is managed by this cluster. Entries
+// without an ownership marker, such as routes to a VPN gateway added by hand,
+// are never listed or modified unless they are adopted legacy entries.
+func (r *routes) ownsEntry(entry binarylane.RouteEntry, clusterName string, routerIsServer bool) bool {
+	entryOwner, _, owned := parseRouteDescription(entry.Description)
+	if owned {
+		return entryOwner == r.owner(clusterName)
+	}
+	return r.isLegacyEntry(entry, routerIsServer)
+}
+
+// isLegacyEntry reports whether an unmarked entry should be adopted as one
+// created before ownership markers were added. Only entries routing part of
+// the cluster CIDR through a server in the VPC are adopted.
+func (r *routes) isLegacyEntry(entry binarylane.RouteEntry, routerIsServer bool) bool {
+	if !r.adoptLegacyEntries || !routerIsServer {
+		return false
+	}
+	if _, _, owned := parseRouteDescription(entry.Description); owned {
+		return false
+	}
+	destination, err := netip.ParsePrefix(entry.Destination)
+	if err != nil {
+		return false
+	}
+	clusterPrefixes, err := parseCIDRs(r.cidr)
+	if err != nil {
+		return false
+	}
+	return prefixesContain(clusterPrefixes, destination)
 }
 
-func (r *routes) ListRoutes(ctx context.Context, clusterName string) ([]*cloudprovider.Route, error) {
+func (r *routes) ListRoutes(ctx context.Context, clusterName string) (_ []*cloudprovider.Route, err error) {
+	defer recordOperation("ListRoutes", time.Now(), &err)
+
 	servers, err := r.client.ListServers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list servers: %w", err)
@@ -27,17 +116,15 @@ func (r *routes) ListRoutes(ctx context.Context, clusterName string) ([]*cloudpr
 	ipToName := make(map[string]string)
 	clusterVpcs := make(map[int64]bool)
 	for _, server := range servers {
-		if !isClusterServer(server.Name, clusterName) {
+		if server.VpcId == nil {
 			continue
 		}
-		if server.VpcId != nil {
-			clusterVpcs[*server.VpcId] = true
-			for _, net := range server.Networks.V4 {
-				if net.Type == "private" {
-					ipToName[net.IpAddress] = server.Name
-					break
-				}
-			}
+		if r.vpcID != 0 && *server.VpcId != r.vpcID {
+			continue
+		}
+		clusterVpcs[*server.VpcId] = true
+		for _, ip := range privateIPs(&server) {
+			ipToName[ip] = server.Name
 		}
 	}
 
@@ -46,6 +133,7 @@ func (r *routes) ListRoutes(ctx context.Context, clusterName string) ([]*cloudpr
 	}
 
 	vpcRoutes := make(map[int64][]*cloudprovider.Route)
+	var blackholes []blackholedRoute
 
 	for vpcID := range clusterVpcs {
 		vpc, err := r.client.GetVpc(ctx, vpcID)
@@ -57,18 +145,33 @@ func (r *routes) ListRoutes(ctx context.Context, clusterName string) ([]*cloudpr
 		}
 
 		for _, routeEntry := range vpc.RouteEntries {
-			nodeName := ipToName[routeEntry.Router]
+			if !r.ownsEntry(routeEntry, clusterName, ipToName[routeEntry.Router] != "") {
+				continue
+			}
+
+			_, nodeName, _ := parseRouteDescription(routeEntry.Description)
+			blackhole := ipToName[routeEntry.Router] == "" && r.isBlackhole(ctx, nodeName, routeEntry.Router)
+
+			if nodeName == "" {
+				nodeName = ipToName[routeEntry.Router]
+			}
 			if nodeName == "" {
 				nodeName = routeEntry.Router
 			}
+			if blackhole {
+				blackholes = append(blackholes, blackholedRoute{vpcID: vpcID, nodeName: nodeName, entry: routeEntry})
+			}
 			vpcRoutes[vpcID] = append(vpcRoutes[vpcID], &cloudprovider.Route{
 				Name:            fmt.Sprintf("%s-%s", nodeName, routeEntry.Destination),
 				TargetNode:      types.NodeName(nodeName),
 				DestinationCIDR: routeEntry.Destination,
+				Blackhole:       blackhole,
 			})
 		}
 	}
 
+	r.blackholeReporter().update(blackholes, r.recorder)
+
 	var allRoutes []*cloudprovider.Route
 	for _, routes := range vpcRoutes {
 		allRoutes = append(allRoutes, routes...)
@@ -77,9 +180,88 @@ func (r *routes) ListRoutes(ctx context.Context, clusterName string) ([]*cloudpr
 	return allRoutes, nil
 }
 
-func (r *routes) CreateRoute(ctx context.Context, clusterName string, nameHint string, route *cloudprovider.Route) error {
+// isBlackhole confirms that the router of a route entry stamped for nodeName
+// no longer belongs to a server. The route controller deletes blackholed
+// routes, so a server the listing may have missed is looked up by name first,
+// and lookup errors are not treated as a blackhole.
+func (r *routes) isBlackhole(ctx context.Context, nodeName, router string) bool {
+	if nodeName == "" {
+		return true
+	}
+	server, err := findServerByNodeName(ctx, r.client, r.nodeLookup, nodeName)
+	if errors.Is(err, binarylane.ErrServerNotFound) {
+		return true
+	}
+	if err != nil {
+		klog.Warningf("Failed to check whether route via %s for node %s is blackholed: %v", router, nodeName, err)
+		return false
+	}
+	return !slices.Contains(privateIPs(server), router)
+}
+
+// blackholedRoute is a route entry whose router is not a private IP of any
+// server.
+type blackholedRoute struct {
+	vpcID    int64
+	nodeName string
+	entry    binarylane.RouteEntry
+}
+
+func (b blackholedRoute) key() string {
+	return fmt.Sprintf("%d/%s/%s", b.vpcID, b.entry.Router, b.entry.Destination)
+}
+
+// blackholeReporter reports each blackholed route once, rather than on every
+// route sync, and keeps the blackholed routes gauge at the number found by the
+// last sync.
+type blackholeReporter struct {
+	mu       sync.Mutex
+	reported map[string]bool
+}
+
+func newBlackholeReporter() *blackholeReporter {
+	return &blackholeReporter{reported: make(map[string]bool)}
+}
+
+// update records the blackholed routes found by a route sync. Routes that are
+// no longer found are forgotten, so they are reported again if they return.
+func (b *blackholeReporter) update(found []blackholedRoute, recorder record.EventRecorder) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	reported := make(map[string]bool, len(found))
+	for _, route := range found {
+		key := route.key()
+		reported[key] = true
+		if !b.reported[key] {
+			reportBlackhole(route, recorder)
+		}
+	}
+	b.reported = reported
+	blackholeRoutes.Set(float64(len(reported)))
+}
+
+func reportBlackhole(route blackholedRoute, recorder record.EventRecorder) {
+	klog.Warningf("Route %s via %s in VPC %d is blackholed, no server has that private IP", route.entry.Destination, route.entry.Router, route.vpcID)
+	if recorder == nil {
+		return
+	}
+	// Routes are not API objects, so the event is attached to the node, as
+	// the route controller does for its own route events. The node has
+	// usually been deleted, so its UID is unknown.
+	nodeRef := &v1.ObjectReference{
+		Kind: "Node",
+		Name: route.nodeName,
+	}
+	recorder.Eventf(nodeRef, v1.EventTypeWarning, "BlackholedRoute",
+		"Route %s via %s in VPC %d is blackholed, no server has that private IP", route.entry.Destination, route.entry.Router, route.vpcID)
+}
+
+func (r *routes) CreateRoute(ctx context.Context, clusterName string, nameHint string, route *cloudprovider.Route) (err error) {
+	defer recordOperation("CreateRoute", time.Now(), &err)
+
 	targetNode := string(route.TargetNode)
-	server, err := r.client.GetServerByName(ctx, targetNode)
+	server, err := findServerByNodeName(ctx, r.client, r.nodeLookup, targetNode)
 	if err != nil {
 		if errors.Is(err, binarylane.ErrServerNotFound) {
 			return fmt.Errorf("target node %s not found", targetNode)
@@ -90,112 +272,252 @@ func (r *routes) CreateRoute(ctx context.Context, clusterName string, nameHint s
 	if server.VpcId == nil {
 		return fmt.Errorf("server %s is not in a VPC", targetNode)
 	}
-
-	var privateIP string
-	for _, net := range server.Networks.V4 {
-		if net.Type == "private" {
-			privateIP = net.IpAddress
-			break
-		}
-	}
-	if privateIP == "" {
-		return fmt.Errorf("server %s has no private IP", targetNode)
+	if r.vpcID != 0 && *server.VpcId != r.vpcID {
+		return fmt.Errorf("server %s is in VPC %d, not the configured VPC %d", targetNode, *server.VpcId, r.vpcID)
 	}
 
-	vpc, err := r.client.GetVpc(ctx, *server.VpcId)
+	// The router must be in the same address family as the destination
+	destination, err := netip.ParsePrefix(route.DestinationCIDR)
 	if err != nil {
-		return fmt.Errorf("failed to get VPC: %w", err)
+		return fmt.Errorf("invalid route destination %q: %w", route.DestinationCIDR, err)
+	}
+	privateIP := privateIPFor(server, destination)
+	if privateIP == "" {
+		if destination.Addr().Is6() {
+			return fmt.Errorf("cannot route IPv6 destination %s: server %s has no private IPv6 address in VPC %d, check that the VPC supports IPv6", route.DestinationCIDR, targetNode, *server.VpcId)
+		}
+		return fmt.Errorf("cannot route IPv4 destination %s: server %s has no private IPv4 address in VPC %d", route.DestinationCIDR, targetNode, *server.VpcId)
 	}
 
-	for _, existingRoute := range vpc.RouteEntries {
-		if existingRoute.Destination == route.DestinationCIDR && existingRoute.Router == privateIP {
-			return nil
+	if !r.keepSourceDestCheck {
+		if err := r.disableSourceDestCheck(ctx, server); err != nil {
+			return err
 		}
 	}
 
-	newRouteEntries := make([]binarylane.RouteEntryRequest, len(vpc.RouteEntries))
-	for i, re := range vpc.RouteEntries {
-		newRouteEntries[i] = binarylane.RouteEntryRequest(re)
+	owner := r.owner(clusterName)
+	description := routeDescription(owner, targetNode)
+	isRoute := func(entry binarylane.RouteEntry) bool {
+		return entry.Destination == route.DestinationCIDR && entry.Router == privateIP
 	}
 
-	newRouteEntries = append(newRouteEntries, binarylane.RouteEntryRequest{
-		Router:      privateIP,
-		Destination: route.DestinationCIDR,
-		Description: func() *string { s := fmt.Sprintf("Kubernetes route for node %s", targetNode); return &s }(),
+	return r.vpcUpdater().update(ctx, *server.VpcId, &routeChange{
+		edit: func(vpc *binarylane.Vpc, entries []binarylane.RouteEntry) ([]binarylane.RouteEntry, error) {
+			if err := r.validateRoute(route.DestinationCIDR, vpc); err != nil {
+				return nil, err
+			}
+			for i, entry := range entries {
+				if !isRoute(entry) {
+					continue
+				}
+				if entryOwner, _, owned := parseRouteDescription(entry.Description); owned {
+					if entryOwner != owner {
+						return nil, fmt.Errorf("route %s via %s is owned by cluster %s", route.DestinationCIDR, privateIP, entryOwner)
+					}
+					return entries, nil
+				}
+				if r.isLegacyEntry(entry, true) {
+					entries[i].Description = &description
+					return entries, nil
+				}
+				// The route already exists, but is not ours to modify
+				klog.Infof("Route %s via %s already exists without an ownership marker, leaving it unchanged", route.DestinationCIDR, privateIP)
+				return entries, nil
+			}
+			return append(entries, binarylane.RouteEntry{
+				Router:      privateIP,
+				Destination: route.DestinationCIDR,
+				Description: &description,
+			}), nil
+		},
+		verify: func(entries []binarylane.RouteEntry) bool {
+			return slices.ContainsFunc(entries, func(entry binarylane.RouteEntry) bool {
+				entryOwner, _, owned := parseRouteDescription(entry.Description)
+				if owned {
+					return isRoute(entry) && entryOwner == owner
+				}
+				return isRoute(entry) && !r.isLegacyEntry(entry, true)
+			})
+		},
 	})
+}
 
-	_, err = r.client.UpdateVpc(ctx, *server.VpcId, binarylane.UpdateVpcRequest{
-		Name:         vpc.Name,
-		RouteEntries: &newRouteEntries,
-	})
+// disableSourceDestCheck makes sure the server accepts packets that are not
+// addressed to it, as pod traffic routed through it would otherwise be dropped.
+func (r *routes) disableSourceDestCheck(ctx context.Context, server *binarylane.Server) error {
+	if check := server.Networks.SourceAndDestinationCheck; check != nil && !*check {
+		return nil
+	}
+
+	action, err := r.client.ChangeSourceAndDestinationCheck(ctx, server.Id, false)
 	if err != nil {
-		return fmt.Errorf("failed to update VPC routes: %w", err)
+		return fmt.Errorf("failed to disable source and destination check on server %s: %w", server.Name, err)
+	}
+	if action.Status != binarylane.Completed {
+		_, err = r.client.WaitForAction(ctx, action.Id, binarylane.WaitOptions{Timeout: actionTimeout})
+		if err != nil {
+			return fmt.Errorf("failed to disable source and destination check on server %s: %w", server.Name, err)
+		}
 	}
 
+	klog.Infof("Disabled source and destination check on server %s (%d)", server.Name, server.Id)
 	return nil
 }
 
-func (r *routes) DeleteRoute(ctx context.Context, clusterName string, route *cloudprovider.Route) error {
+func (r *routes) DeleteRoute(ctx context.Context, clusterName string, route *cloudprovider.Route) (err error) {
+	defer recordOperation("DeleteRoute", time.Now(), &err)
+
 	targetNode := string(route.TargetNode)
-	server, err := r.client.GetServerByName(ctx, targetNode)
+	server, err := findServerByNodeName(ctx, r.client, r.nodeLookup, targetNode)
 	if err != nil {
 		if errors.Is(err, binarylane.ErrServerNotFound) {
-			return nil
+			return r.deleteOrphanedRoute(ctx, clusterName, route, nil)
 		}
 		return fmt.Errorf("failed to get server: %w", err)
 	}
 
+	// A blackholed route of an existing server points at a private IP the
+	// server no longer has, so it cannot be found by the current one.
+	if route.Blackhole {
+		return r.deleteOrphanedRoute(ctx, clusterName, route, privateIPs(server))
+	}
+
 	if server.VpcId == nil {
 		return nil
 	}
 
-	var privateIP string
-	for _, net := range server.Networks.V4 {
-		if net.Type == "private" {
-			privateIP = net.IpAddress
-			break
-		}
+	destination, err := netip.ParsePrefix(route.DestinationCIDR)
+	if err != nil {
+		return fmt.Errorf("invalid route destination %q: %w", route.DestinationCIDR, err)
 	}
+	privateIP := privateIPFor(server, destination)
 	if privateIP == "" {
 		return nil
 	}
 
-	vpc, err := r.client.GetVpc(ctx, *server.VpcId)
+	isOwnedRoute := func(entry binarylane.RouteEntry) bool {
+		return entry.Destination == route.DestinationCIDR && entry.Router == privateIP && r.ownsEntry(entry, clusterName, true)
+	}
+
+	err = r.vpcUpdater().update(ctx, *server.VpcId, &routeChange{
+		edit: func(vpc *binarylane.Vpc, entries []binarylane.RouteEntry) ([]binarylane.RouteEntry, error) {
+			return slices.DeleteFunc(entries, isOwnedRoute), nil
+		},
+		verify: func(entries []binarylane.RouteEntry) bool {
+			return !slices.ContainsFunc(entries, isOwnedRoute)
+		},
+	})
+	if errors.Is(err, binarylane.ErrVpcNotFound) {
+		return nil
+	}
+	return err
+}
+
+// deleteOrphanedRoute deletes a blackholed route, whose router is no longer a
+// private IP of the node's server, or whose server no longer exists. The router
+// IP of such a route is unknown, so the entry is found by its destination and
+// ownership marker in the VPCs that ListRoutes reads. Entries routed through
+// one of currentIPs are the node's live routes, and are left alone.
+func (r *routes) deleteOrphanedRoute(ctx context.Context, clusterName string, route *cloudprovider.Route, currentIPs []string) error {
+	owner := r.owner(clusterName)
+	isOrphanedRoute := func(entry binarylane.RouteEntry) bool {
+		entryOwner, nodeName, owned := parseRouteDescription(entry.Description)
+		return owned && entryOwner == owner && nodeName == string(route.TargetNode) && entry.Destination == route.DestinationCIDR &&
+			!slices.Contains(currentIPs, entry.Router)
+	}
+
+	vpcIDs, err := r.clusterVpcIDs(ctx)
 	if err != nil {
-		if errors.Is(err, binarylane.ErrVpcNotFound) {
-			return nil
+		return err
+	}
+	for _, vpcID := range vpcIDs {
+		vpc, err := r.client.GetVpc(ctx, vpcID)
+		if err != nil {
+			if errors.Is(err, binarylane.ErrVpcNotFound) {
+				continue
+			}
+			return fmt.Errorf("failed to get VPC %d: %w", vpcID, err)
+		}
+		if !slices.ContainsFunc(vpc.RouteEntries, isOrphanedRoute) {
+			continue
 		}
-		return fmt.Errorf("failed to get VPC: %w", err)
+
+		err = r.vpcUpdater().update(ctx, vpcID, &routeChange{
+			edit: func(vpc *binarylane.Vpc, entries []binarylane.RouteEntry) ([]binarylane.RouteEntry, error) {
+				return slices.DeleteFunc(entries, isOrphanedRoute), nil
+			},
+			verify: func(entries []binarylane.RouteEntry) bool {
+				return !slices.ContainsFunc(entries, isOrphanedRoute)
+			},
+		})
+		if err != nil && !errors.Is(err, binarylane.ErrVpcNotFound) {
+			return err
+		}
+		klog.Infof("Deleted blackholed route %s of node %s from VPC %d", route.DestinationCIDR, route.TargetNode, vpcID)
 	}
 
-	var newRouteEntries []binarylane.RouteEntryRequest
-	found := false
-	for _, re := range vpc.RouteEntries {
-		if re.Destination == route.DestinationCIDR && re.Router == privateIP {
-			found = true
-			continue
+	return nil
+}
+
+// clusterVpcIDs returns the configured VPC, or otherwise the VPCs of the
+// account's servers.
+func (r *routes) clusterVpcIDs(ctx context.Context) ([]int64, error) {
+	if r.vpcID != 0 {
+		return []int64{r.vpcID}, nil
+	}
+
+	servers, err := r.client.ListServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+	var vpcIDs []int64
+	for _, server := range servers {
+		if server.VpcId != nil && !slices.Contains(vpcIDs, *server.VpcId) {
+			vpcIDs = append(vpcIDs, *server.VpcId)
 		}
-		newRouteEntries = append(newRouteEntries, binarylane.RouteEntryRequest(re))
 	}
+	return vpcIDs, nil
+}
 
-	if !found {
-		return nil
+// validateRoute checks that the destination is inside the cluster CIDR, and
+// that the cluster CIDR does not overlap the VPC's own address range.
+func (r *routes) validateRoute(destinationCIDR string, vpc *binarylane.Vpc) error {
+	clusterPrefixes, err := parseCIDRs(r.cidr)
+	if err != nil {
+		return fmt.Errorf("invalid cluster CIDR: %w", err)
 	}
 
-	_, err = r.client.UpdateVpc(ctx, *server.VpcId, binarylane.UpdateVpcRequest{
-		Name:         vpc.Name,
-		RouteEntries: &newRouteEntries,
-	})
+	destination, err := netip.ParsePrefix(destinationCIDR)
+	if err != nil {
+		return fmt.Errorf("invalid route destination %q: %w", destinationCIDR, err)
+	}
+
+	if !prefixesContain(clusterPrefixes, destination) {
+		return fmt.Errorf("route destination %s is outside of cluster CIDR %s", destinationCIDR, r.cidr)
+	}
+
+	if vpc.IpRange == "" {
+		return nil
+	}
+	vpcRange, err := netip.ParsePrefix(vpc.IpRange)
 	if err != nil {
-		return fmt.Errorf("failed to update VPC routes: %w", err)
+		return fmt.Errorf("VPC %d has invalid IP range %q: %w", vpc.Id, vpc.IpRange, err)
+	}
+	for _, prefix := range clusterPrefixes {
+		if prefix.Overlaps(vpcRange) {
+			return fmt.Errorf("cluster CIDR %s overlaps VPC %d IP range %s", prefix, vpc.Id, vpc.IpRange)
+		}
 	}
 
 	return nil
 }
 
-func isClusterServer(serverName, clusterName string) bool {
-	if clusterName == "" {
-		return true
+// prefixesContain reports whether prefix is inside any of prefixes.
+func prefixesContain(prefixes []netip.Prefix, prefix netip.Prefix) bool {
+	for _, p := range prefixes {
+		if p.Bits() <= prefix.Bits() && p.Contains(prefix.Addr()) {
+			return true
+		}
 	}
-	return strings.HasPrefix(serverName, clusterName)
+	return false
 }
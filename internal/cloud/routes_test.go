@@ -2,11 +2,16 @@ package cloud
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
 )
 
 func TestListRoutes(t *testing.T) {
@@ -16,6 +21,7 @@ func TestListRoutes(t *testing.T) {
 		servers       map[int64]*binarylane.Server
 		vpcs          map[int64]*binarylane.Vpc
 		cidr          string
+		adoptLegacy   bool
 		wantRoutes    int
 		wantErr       bool
 		wantErrPrefix string
@@ -47,9 +53,10 @@ func TestListRoutes(t *testing.T) {
 					},
 				},
 			},
-			cidr:       "10.244.0.0/16",
-			wantRoutes: 1,
-			wantErr:    false,
+			cidr:        "10.244.0.0/16",
+			adoptLegacy: true,
+			wantRoutes:  1,
+			wantErr:     false,
 		},
 		{
 			name: "servers without VPC",
@@ -66,7 +73,7 @@ func TestListRoutes(t *testing.T) {
 			wantErr:    false,
 		},
 		{
-			name: "filters servers by cluster name",
+			name: "ignores unmarked entries by default",
 			servers: map[int64]*binarylane.Server{
 				1: {
 					Id:    1,
@@ -106,7 +113,136 @@ func TestListRoutes(t *testing.T) {
 				},
 			},
 			cidr:       "10.244.0.0/16",
-			wantRoutes: 2,
+			wantRoutes: 0,
+			wantErr:    false,
+		},
+		{
+			name: "adopts legacy entries",
+			servers: map[int64]*binarylane.Server{
+				1: {
+					Id:    1,
+					Name:  "test-cluster-node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.240.0.10"},
+						},
+					},
+				},
+				2: {
+					Id:    2,
+					Name:  "other-cluster-node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.240.0.20"},
+						},
+					},
+				},
+			},
+			vpcs: map[int64]*binarylane.Vpc{
+				100: {
+					Id:   100,
+					Name: "test-vpc",
+					RouteEntries: []binarylane.RouteEntry{
+						{
+							Router:      "10.240.0.10",
+							Destination: "10.244.1.0/24",
+						},
+						{
+							Router:      "10.240.0.20",
+							Destination: "10.244.2.0/24",
+						},
+					},
+				},
+			},
+			cidr:        "10.244.0.0/16",
+			adoptLegacy: true,
+			wantRoutes:  2,
+			wantErr:     false,
+		},
+		{
+			name: "never adopts foreign entries",
+			servers: map[int64]*binarylane.Server{
+				1: {
+					Id:    1,
+					Name:  "test-cluster-node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.240.0.10"},
+						},
+					},
+				},
+			},
+			vpcs: map[int64]*binarylane.Vpc{
+				100: {
+					Id:   100,
+					Name: "test-vpc",
+					RouteEntries: []binarylane.RouteEntry{
+						{
+							// Router is a VPN gateway, not a server
+							Router:      "10.240.0.1",
+							Destination: "10.244.1.0/24",
+						},
+						{
+							// Destination is outside of the cluster CIDR
+							Router:      "10.240.0.10",
+							Destination: "192.168.0.0/24",
+							Description: toPtr("office VPN"),
+						},
+					},
+				},
+			},
+			cidr:        "10.244.0.0/16",
+			adoptLegacy: true,
+			wantRoutes:  0,
+			wantErr:     false,
+		},
+		{
+			name: "filters routes by ownership marker",
+			servers: map[int64]*binarylane.Server{
+				1: {
+					Id:    1,
+					Name:  "test-cluster-node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.240.0.10"},
+						},
+					},
+				},
+				2: {
+					Id:    2,
+					Name:  "test-cluster-2-node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.240.0.20"},
+						},
+					},
+				},
+			},
+			vpcs: map[int64]*binarylane.Vpc{
+				100: {
+					Id:   100,
+					Name: "test-vpc",
+					RouteEntries: []binarylane.RouteEntry{
+						{
+							Router:      "10.240.0.10",
+							Destination: "10.244.1.0/24",
+							Description: toPtr(routeDescription("test-cluster", "test-cluster-node-1")),
+						},
+						{
+							Router:      "10.240.0.20",
+							Destination: "10.244.2.0/24",
+							Description: toPtr(routeDescription("test-cluster-2", "test-cluster-2-node-1")),
+						},
+					},
+				},
+			},
+			cidr:       "10.244.0.0/16",
+			wantRoutes: 1,
 			wantErr:    false,
 		},
 	}
@@ -118,8 +254,9 @@ func TestListRoutes(t *testing.T) {
 				vpcs:    tt.vpcs,
 			}
 			r := &routes{
-				client: mock,
-				cidr:   tt.cidr,
+				client:             mock,
+				cidr:               tt.cidr,
+				adoptLegacyEntries: tt.adoptLegacy,
 			}
 
 			routeList, err := r.ListRoutes(context.Background(), "test-cluster")
@@ -225,6 +362,121 @@ func TestCreateRoute(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "route owned by another cluster",
+			servers: map[int64]*binarylane.Server{
+				1: {
+					Id:    1,
+					Name:  "node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.240.0.10"},
+						},
+					},
+				},
+			},
+			vpcs: map[int64]*binarylane.Vpc{
+				100: {
+					Id:   100,
+					Name: "test-vpc",
+					RouteEntries: []binarylane.RouteEntry{
+						{
+							Router:      "10.240.0.10",
+							Destination: "10.244.1.0/24",
+							Description: toPtr(routeDescription("other-cluster", "node-1")),
+						},
+					},
+				},
+			},
+			route: &cloudprovider.Route{
+				TargetNode:      types.NodeName("node-1"),
+				DestinationCIDR: "10.244.1.0/24",
+			},
+			wantErr: true,
+		},
+		{
+			name: "destination outside cluster CIDR",
+			servers: map[int64]*binarylane.Server{
+				1: {
+					Id:    1,
+					Name:  "node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.240.0.10"},
+						},
+					},
+				},
+			},
+			vpcs: map[int64]*binarylane.Vpc{
+				100: {
+					Id:           100,
+					Name:         "test-vpc",
+					RouteEntries: []binarylane.RouteEntry{},
+				},
+			},
+			route: &cloudprovider.Route{
+				TargetNode:      types.NodeName("node-1"),
+				DestinationCIDR: "192.168.1.0/24",
+			},
+			wantErr: true,
+		},
+		{
+			name: "cluster CIDR overlaps VPC range",
+			servers: map[int64]*binarylane.Server{
+				1: {
+					Id:    1,
+					Name:  "node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.244.0.10"},
+						},
+					},
+				},
+			},
+			vpcs: map[int64]*binarylane.Vpc{
+				100: {
+					Id:           100,
+					Name:         "test-vpc",
+					IpRange:      "10.244.0.0/24",
+					RouteEntries: []binarylane.RouteEntry{},
+				},
+			},
+			route: &cloudprovider.Route{
+				TargetNode:      types.NodeName("node-1"),
+				DestinationCIDR: "10.244.1.0/24",
+			},
+			wantErr: true,
+		},
+		{
+			name: "IPv6 route without private IPv6 address",
+			servers: map[int64]*binarylane.Server{
+				1: {
+					Id:    1,
+					Name:  "node-1",
+					VpcId: &vpcID,
+					Networks: binarylane.Networks{
+						V4: []binarylane.Network{
+							{Type: "private", IpAddress: "10.240.0.10"},
+						},
+					},
+				},
+			},
+			vpcs: map[int64]*binarylane.Vpc{
+				100: {
+					Id:           100,
+					Name:         "test-vpc",
+					RouteEntries: []binarylane.RouteEntry{},
+				},
+			},
+			route: &cloudprovider.Route{
+				TargetNode:      types.NodeName("node-1"),
+				DestinationCIDR: "fd00:10:244:1::/64",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -235,7 +487,7 @@ func TestCreateRoute(t *testing.T) {
 			}
 			r := &routes{
 				client: mock,
-				cidr:   "10.244.0.0/16",
+				cidr:   "10.244.0.0/16,fd00:10:244::/56",
 			}
 
 			err := r.CreateRoute(context.Background(), "test-cluster", "hint", tt.route)
@@ -346,3 +598,566 @@ func TestDeleteRoute(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateRouteStampsOwnership(t *testing.T) {
+	vpcID := int64(100)
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {
+				Id:    1,
+				Name:  "node-1",
+				VpcId: &vpcID,
+				Networks: binarylane.Networks{
+					V4: []binarylane.Network{
+						{Type: "private", IpAddress: "10.240.0.10"},
+					},
+				},
+			},
+		},
+		vpcs: map[int64]*binarylane.Vpc{
+			100: {
+				Id:   100,
+				Name: "test-vpc",
+				RouteEntries: []binarylane.RouteEntry{
+					{
+						Router:      "10.240.0.10",
+						Destination: "10.244.1.0/24",
+					},
+				},
+			},
+		},
+	}
+	r := &routes{
+		client:             mock,
+		cidr:               "10.244.0.0/16",
+		clusterID:          "prod",
+		adoptLegacyEntries: true,
+	}
+
+	for _, cidr := range []string{"10.244.1.0/24", "10.244.2.0/24"} {
+		err := r.CreateRoute(context.Background(), "kubernetes", "hint", &cloudprovider.Route{
+			TargetNode:      types.NodeName("node-1"),
+			DestinationCIDR: cidr,
+		})
+		if err != nil {
+			t.Fatalf("CreateRoute() error = %v", err)
+		}
+	}
+
+	entries := mock.vpcs[100].RouteEntries
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 route entries, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		owner, node, ok := parseRouteDescription(entry.Description)
+		if !ok || owner != "prod" || node != "node-1" {
+			t.Errorf("route %s description = %v, want owned by prod for node-1", entry.Destination, entry.Description)
+		}
+	}
+}
+
+func TestDeleteRouteIgnoresOtherClusters(t *testing.T) {
+	vpcID := int64(100)
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {
+				Id:    1,
+				Name:  "node-1",
+				VpcId: &vpcID,
+				Networks: binarylane.Networks{
+					V4: []binarylane.Network{
+						{Type: "private", IpAddress: "10.240.0.10"},
+					},
+				},
+			},
+		},
+		vpcs: map[int64]*binarylane.Vpc{
+			100: {
+				Id:   100,
+				Name: "test-vpc",
+				RouteEntries: []binarylane.RouteEntry{
+					{
+						Router:      "10.240.0.10",
+						Destination: "10.244.1.0/24",
+						Description: toPtr(routeDescription("prod-2", "node-1")),
+					},
+				},
+			},
+		},
+	}
+	r := &routes{
+		client:    mock,
+		cidr:      "10.244.0.0/16",
+		clusterID: "prod",
+	}
+
+	err := r.DeleteRoute(context.Background(), "kubernetes", &cloudprovider.Route{
+		TargetNode:      types.NodeName("node-1"),
+		DestinationCIDR: "10.244.1.0/24",
+	})
+	if err != nil {
+		t.Fatalf("DeleteRoute() error = %v", err)
+	}
+	if len(mock.vpcs[100].RouteEntries) != 1 {
+		t.Errorf("route owned by another cluster was deleted")
+	}
+}
+
+func TestRoutesLeaveForeignEntriesAlone(t *testing.T) {
+	vpcID := int64(100)
+	foreign := []binarylane.RouteEntry{
+		{
+			Router:      "10.240.0.10",
+			Destination: "10.244.1.0/24",
+			Description: toPtr("added by hand"),
+		},
+		{
+			Router:      "10.240.0.1",
+			Destination: "192.168.0.0/24",
+		},
+	}
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {
+				Id:    1,
+				Name:  "node-1",
+				VpcId: &vpcID,
+				Networks: binarylane.Networks{
+					V4: []binarylane.Network{
+						{Type: "private", IpAddress: "10.240.0.10"},
+					},
+				},
+			},
+		},
+		vpcs: map[int64]*binarylane.Vpc{
+			100: {
+				Id:           100,
+				Name:         "test-vpc",
+				RouteEntries: slices.Clone(foreign),
+			},
+		},
+	}
+	r := &routes{
+		client:    mock,
+		cidr:      "10.244.0.0/16",
+		clusterID: "prod",
+	}
+
+	route := &cloudprovider.Route{
+		TargetNode:      types.NodeName("node-1"),
+		DestinationCIDR: "10.244.1.0/24",
+	}
+	if err := r.CreateRoute(context.Background(), "kubernetes", "hint", route); err != nil {
+		t.Fatalf("CreateRoute() error = %v", err)
+	}
+	if err := r.DeleteRoute(context.Background(), "kubernetes", route); err != nil {
+		t.Fatalf("DeleteRoute() error = %v", err)
+	}
+
+	if !routeEntriesEqual(mock.vpcs[100].RouteEntries, foreign) {
+		t.Errorf("route entries = %+v, want foreign entries unchanged %+v", mock.vpcs[100].RouteEntries, foreign)
+	}
+}
+
+func TestListRoutesReportsBlackholes(t *testing.T) {
+	vpcID := int64(100)
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {
+				Id:    1,
+				Name:  "node-1",
+				VpcId: &vpcID,
+				Networks: binarylane.Networks{
+					V4: []binarylane.Network{
+						{Type: "private", IpAddress: "10.240.0.10"},
+					},
+				},
+			},
+		},
+		vpcs: map[int64]*binarylane.Vpc{
+			100: {
+				Id:   100,
+				Name: "test-vpc",
+				RouteEntries: []binarylane.RouteEntry{
+					{
+						Router:      "10.240.0.10",
+						Destination: "10.244.1.0/24",
+						Description: toPtr(routeDescription("prod", "node-1")),
+					},
+					{
+						Router:      "10.240.0.20",
+						Destination: "10.244.2.0/24",
+						Description: toPtr(routeDescription("prod", "node-2")),
+					},
+				},
+			},
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &routes{
+		client:    mock,
+		cidr:      "10.244.0.0/16",
+		clusterID: "prod",
+		recorder:  recorder,
+	}
+
+	routeList, err := r.ListRoutes(context.Background(), "kubernetes")
+	if err != nil {
+		t.Fatalf("ListRoutes() error = %v", err)
+	}
+
+	if len(routeList) != 2 {
+		t.Fatalf("ListRoutes() returned %d routes, want 2", len(routeList))
+	}
+	for _, route := range routeList {
+		if want := route.TargetNode == "node-2"; route.Blackhole != want {
+			t.Errorf("route %s Blackhole = %v, want %v", route.Name, route.Blackhole, want)
+		}
+	}
+
+	if got := gaugeValue(t, blackholeRoutes); got != 1 {
+		t.Errorf("blackhole routes gauge = %v, want 1", got)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "BlackholedRoute") || !strings.Contains(event, "10.244.2.0/24") {
+			t.Errorf("unexpected event %q", event)
+		}
+	default:
+		t.Errorf("expected a BlackholedRoute event")
+	}
+
+	// A blackholed route is only reported once
+	if _, err := r.ListRoutes(context.Background(), "kubernetes"); err != nil {
+		t.Fatalf("ListRoutes() error = %v", err)
+	}
+	if got := gaugeValue(t, blackholeRoutes); got != 1 {
+		t.Errorf("blackhole routes gauge = %v after second sync, want 1", got)
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("unexpected event %q after second sync", event)
+	default:
+	}
+
+	mock.vpcs[100].RouteEntries = mock.vpcs[100].RouteEntries[:1]
+	if _, err := r.ListRoutes(context.Background(), "kubernetes"); err != nil {
+		t.Fatalf("ListRoutes() error = %v", err)
+	}
+	if got := gaugeValue(t, blackholeRoutes); got != 0 {
+		t.Errorf("blackhole routes gauge = %v after the route is deleted, want 0", got)
+	}
+}
+
+func TestDeleteBlackholedRoute(t *testing.T) {
+	vpcID := int64(100)
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {
+				Id:    1,
+				Name:  "node-1",
+				VpcId: &vpcID,
+				Networks: binarylane.Networks{
+					V4: []binarylane.Network{
+						{Type: "private", IpAddress: "10.240.0.10"},
+					},
+				},
+			},
+		},
+		vpcs: map[int64]*binarylane.Vpc{
+			100: {
+				Id:   100,
+				Name: "test-vpc",
+				RouteEntries: []binarylane.RouteEntry{
+					{
+						Router:      "10.240.0.10",
+						Destination: "10.244.1.0/24",
+						Description: toPtr(routeDescription("prod", "node-1")),
+					},
+					{
+						Router:      "10.240.0.20",
+						Destination: "10.244.2.0/24",
+						Description: toPtr(routeDescription("prod", "node-2")),
+					},
+					{
+						Router:      "10.240.0.30",
+						Destination: "10.244.2.0/24",
+						Description: toPtr(routeDescription("staging", "node-2")),
+					},
+				},
+			},
+		},
+	}
+	r := &routes{
+		client:    mock,
+		cidr:      "10.244.0.0/16",
+		clusterID: "prod",
+	}
+
+	routeList, err := r.ListRoutes(context.Background(), "kubernetes")
+	if err != nil {
+		t.Fatalf("ListRoutes() error = %v", err)
+	}
+	var blackholed *cloudprovider.Route
+	for _, route := range routeList {
+		if route.Blackhole {
+			blackholed = route
+		}
+	}
+	if blackholed == nil {
+		t.Fatalf("ListRoutes() = %v, want a blackholed route", routeList)
+	}
+
+	if err := r.DeleteRoute(context.Background(), "kubernetes", blackholed); err != nil {
+		t.Fatalf("DeleteRoute() error = %v", err)
+	}
+
+	want := []binarylane.RouteEntry{
+		mock.vpcs[100].RouteEntries[0],
+		{
+			Router:      "10.240.0.30",
+			Destination: "10.244.2.0/24",
+			Description: toPtr(routeDescription("staging", "node-2")),
+		},
+	}
+	if !routeEntriesEqual(mock.vpcs[100].RouteEntries, want) {
+		t.Errorf("route entries = %+v, want %+v", mock.vpcs[100].RouteEntries, want)
+	}
+}
+
+func TestDeleteBlackholedRouteChangedIP(t *testing.T) {
+	vpcID := int64(100)
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {
+				Id:    1,
+				Name:  "node-1",
+				VpcId: &vpcID,
+				Networks: binarylane.Networks{
+					V4: []binarylane.Network{
+						{Type: "private", IpAddress: "10.240.0.11"},
+					},
+				},
+			},
+		},
+		vpcs: map[int64]*binarylane.Vpc{
+			100: {
+				Id:   100,
+				Name: "test-vpc",
+				RouteEntries: []binarylane.RouteEntry{
+					{
+						Router:      "10.240.0.10",
+						Destination: "10.244.1.0/24",
+						Description: toPtr(routeDescription("prod", "node-1")),
+					},
+					{
+						Router:      "10.240.0.11",
+						Destination: "10.244.1.0/24",
+						Description: toPtr(routeDescription("prod", "node-1")),
+					},
+				},
+			},
+		},
+	}
+	r := &routes{
+		client:    mock,
+		cidr:      "10.244.0.0/16",
+		clusterID: "prod",
+	}
+
+	routeList, err := r.ListRoutes(context.Background(), "kubernetes")
+	if err != nil {
+		t.Fatalf("ListRoutes() error = %v", err)
+	}
+	var blackholed *cloudprovider.Route
+	for _, route := range routeList {
+		if route.Blackhole {
+			blackholed = route
+		}
+	}
+	if blackholed == nil {
+		t.Fatalf("ListRoutes() = %v, want a blackholed route", routeList)
+	}
+
+	if err := r.DeleteRoute(context.Background(), "kubernetes", blackholed); err != nil {
+		t.Fatalf("DeleteRoute() error = %v", err)
+	}
+
+	want := []binarylane.RouteEntry{
+		{
+			Router:      "10.240.0.11",
+			Destination: "10.244.1.0/24",
+			Description: toPtr(routeDescription("prod", "node-1")),
+		},
+	}
+	if !routeEntriesEqual(mock.vpcs[100].RouteEntries, want) {
+		t.Errorf("route entries = %+v, want %+v", mock.vpcs[100].RouteEntries, want)
+	}
+}
+
+func gaugeValue(t *testing.T, m metrics.GaugeMetric) float64 {
+	t.Helper()
+	value, err := testutil.GetGaugeMetricValue(m)
+	if err != nil {
+		t.Fatalf("failed to read gauge: %v", err)
+	}
+	return value
+}
+
+func TestDualStackRoutes(t *testing.T) {
+	vpcID := int64(100)
+	mock := &mockClient{
+		servers: map[int64]*binarylane.Server{
+			1: {
+				Id:    1,
+				Name:  "node-1",
+				VpcId: &vpcID,
+				Networks: binarylane.Networks{
+					V4: []binarylane.Network{
+						{Type: "public", IpAddress: "43.229.63.57"},
+						{Type: "private", IpAddress: "10.240.0.10"},
+					},
+					V6: []binarylane.Network{
+						{Type: "public", IpAddress: "2401:fc00::10"},
+						{Type: "private", IpAddress: "fd00:240::10"},
+					},
+				},
+			},
+		},
+		vpcs: map[int64]*binarylane.Vpc{
+			100: {
+				Id:      100,
+				Name:    "test-vpc",
+				IpRange: "10.240.0.0/16",
+			},
+		},
+	}
+	r := &routes{
+		client:    mock,
+		cidr:      "10.244.0.0/16,fd00:10:244::/56",
+		clusterID: "prod",
+	}
+
+	want := map[string]string{
+		"10.244.1.0/24":      "10.240.0.10",
+		"fd00:10:244:1::/64": "fd00:240::10",
+	}
+	for destination := range want {
+		err := r.CreateRoute(context.Background(), "kubernetes", "hint", &cloudprovider.Route{
+			TargetNode:      types.NodeName("node-1"),
+			DestinationCIDR: destination,
+		})
+		if err != nil {
+			t.Fatalf("CreateRoute(%s) error = %v", destination, err)
+		}
+	}
+
+	entries := mock.vpcs[100].RouteEntries
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d route entries, got %d", len(want), len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Router != want[entry.Destination] {
+			t.Errorf("route %s router = %s, want %s", entry.Destination, entry.Router, want[entry.Destination])
+		}
+	}
+
+	routeList, err := r.ListRoutes(context.Background(), "kubernetes")
+	if err != nil {
+		t.Fatalf("ListRoutes() error = %v", err)
+	}
+	for _, route := range routeList {
+		if route.Blackhole || route.TargetNode != "node-1" {
+			t.Errorf("route %s = %+v, want a live route to node-1", route.DestinationCIDR, route)
+		}
+	}
+
+	err = r.DeleteRoute(context.Background(), "kubernetes", &cloudprovider.Route{
+		TargetNode:      types.NodeName("node-1"),
+		DestinationCIDR: "fd00:10:244:1::/64",
+	})
+	if err != nil {
+		t.Fatalf("DeleteRoute() error = %v", err)
+	}
+	if entries := mock.vpcs[100].RouteEntries; len(entries) != 1 || entries[0].Destination != "10.244.1.0/24" {
+		t.Errorf("route entries after deleting IPv6 route = %+v, want only the IPv4 route", entries)
+	}
+}
+
+func TestCreateRouteDisablesSourceDestinationCheck(t *testing.T) {
+	vpcID := int64(100)
+	tests := []struct {
+		name                string
+		keepSourceDestCheck bool
+		actions             map[int64]*binarylane.Action
+		wantCheck           *bool
+		wantErr             bool
+	}{
+		{
+			name:      "disables check on target server",
+			wantCheck: toPtr(false),
+		},
+		{
+			name:                "opted out",
+			keepSourceDestCheck: true,
+			wantCheck:           toPtr(true),
+		},
+		{
+			name: "action errored",
+			actions: map[int64]*binarylane.Action{
+				1: {Id: 1, Type: "change_source_and_destination_check", Status: binarylane.Errored},
+			},
+			wantCheck: toPtr(true),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{
+				servers: map[int64]*binarylane.Server{
+					1: {
+						Id:    1,
+						Name:  "node-1",
+						VpcId: &vpcID,
+						Networks: binarylane.Networks{
+							V4: []binarylane.Network{
+								{Type: "private", IpAddress: "10.240.0.10"},
+							},
+							SourceAndDestinationCheck: toPtr(true),
+						},
+					},
+				},
+				vpcs: map[int64]*binarylane.Vpc{
+					100: {Id: 100, Name: "test-vpc"},
+				},
+				actions: tt.actions,
+			}
+			r := &routes{
+				client:              mock,
+				cidr:                "10.244.0.0/16",
+				keepSourceDestCheck: tt.keepSourceDestCheck,
+			}
+
+			err := r.CreateRoute(context.Background(), "kubernetes", "hint", &cloudprovider.Route{
+				TargetNode:      types.NodeName("node-1"),
+				DestinationCIDR: "10.244.1.0/24",
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := mock.servers[1].Networks.SourceAndDestinationCheck; !ptrEqual(got, tt.wantCheck) {
+				t.Errorf("SourceAndDestinationCheck = %v, want %v", *got, *tt.wantCheck)
+			}
+			// Routes are only created once traffic to them will be accepted
+			wantRoutes := 1
+			if tt.wantErr {
+				wantRoutes = 0
+			}
+			if len(mock.vpcs[100].RouteEntries) != wantRoutes {
+				t.Errorf("got %d route entries, want %d", len(mock.vpcs[100].RouteEntries), wantRoutes)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+)
+
+const (
+	routeUpdateTimeout     = 2 * time.Minute
+	routeUpdateMaxAttempts = 5
+	routeUpdateRetryDelay  = 200 * time.Millisecond
+)
+
+// routeChange is an edit to the route table of a VPC. The edit is applied to
+// the current entries on every attempt, so it must be idempotent, and verify
+// reports whether a table read back after writing still reflects the edit.
+type routeChange struct {
+	edit   func(vpc *binarylane.Vpc, entries []binarylane.RouteEntry) ([]binarylane.RouteEntry, error)
+	verify func(entries []binarylane.RouteEntry) bool
+	done   chan error
+}
+
+// vpcRouteUpdater serialises changes to VPC route tables. The API only
+// supports replacing the whole table, even with PATCH, so concurrent
+// read-modify-write cycles would drop each other's entries. Changes queued
+// while a VPC is being written are batched into its next write, which is read
+// back to detect updates lost to writers outside this process.
+type vpcRouteUpdater struct {
+	client cloudClientInterface
+	// onWrite is called after every write, e.g. to invalidate a cache.
+	onWrite func(vpcID int64)
+
+	maxAttempts int
+	retryDelay  time.Duration
+
+	mu      sync.Mutex
+	pending map[int64][]*routeChange
+}
+
+func newVpcRouteUpdater(client cloudClientInterface) *vpcRouteUpdater {
+	return &vpcRouteUpdater{
+		client:      client,
+		maxAttempts: routeUpdateMaxAttempts,
+		retryDelay:  routeUpdateRetryDelay,
+		pending:     make(map[int64][]*routeChange),
+	}
+}
+
+// update queues a change and waits until it has been written and verified.
+func (u *vpcRouteUpdater) update(ctx context.Context, vpcID int64, change *routeChange) error {
+	change.done = make(chan error, 1)
+
+	u.mu.Lock()
+	queue, running := u.pending[vpcID]
+	u.pending[vpcID] = append(queue, change)
+	u.mu.Unlock()
+
+	// The VPC has a worker while it has an entry in pending
+	if !running {
+		go u.run(vpcID)
+	}
+
+	select {
+	case err := <-change.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run applies queued changes to a VPC until its queue is empty.
+func (u *vpcRouteUpdater) run(vpcID int64) {
+	for {
+		u.mu.Lock()
+		batch := u.pending[vpcID]
+		if len(batch) == 0 {
+			delete(u.pending, vpcID)
+			u.mu.Unlock()
+			return
+		}
+		u.pending[vpcID] = batch[:0:0]
+		u.mu.Unlock()
+
+		// Changes outlive the requests that queued them, so the write is
+		// bounded by its own timeout rather than a caller's context.
+		ctx, cancel := context.WithTimeout(context.Background(), routeUpdateTimeout)
+		u.apply(ctx, vpcID, batch)
+		cancel()
+	}
+}
+
+func (u *vpcRouteUpdater) apply(ctx context.Context, vpcID int64, batch []*routeChange) {
+	fail := func(changes []*routeChange, err error) {
+		for _, change := range changes {
+			change.done <- err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		vpc, err := u.client.GetVpc(ctx, vpcID)
+		if err != nil {
+			fail(batch, fmt.Errorf("failed to get VPC: %w", err))
+			return
+		}
+
+		entries := slices.Clone(vpc.RouteEntries)
+		applied := batch[:0:0]
+		for _, change := range batch {
+			edited, err := change.edit(vpc, entries)
+			if err != nil {
+				change.done <- err
+				continue
+			}
+			entries = edited
+			applied = append(applied, change)
+		}
+		batch = applied
+		if len(batch) == 0 {
+			return
+		}
+
+		if !routeEntriesEqual(entries, vpc.RouteEntries) {
+			requests := make([]binarylane.RouteEntryRequest, len(entries))
+			for i, entry := range entries {
+				requests[i] = binarylane.RouteEntryRequest(entry)
+			}
+			_, err := u.client.PatchVpc(ctx, vpcID, binarylane.PatchVpcRequest{
+				RouteEntries: &requests,
+			})
+			if u.onWrite != nil {
+				u.onWrite(vpcID)
+			}
+			if err != nil {
+				fail(batch, fmt.Errorf("failed to update VPC routes: %w", err))
+				return
+			}
+
+			// Read back, as another writer may have replaced the table
+			// between our read and write
+			if vpc, err = u.client.GetVpc(ctx, vpcID); err != nil {
+				fail(batch, fmt.Errorf("failed to verify VPC routes: %w", err))
+				return
+			}
+		}
+
+		lost := batch[:0:0]
+		for _, change := range batch {
+			if change.verify(vpc.RouteEntries) {
+				change.done <- nil
+			} else {
+				lost = append(lost, change)
+			}
+		}
+		if len(lost) == 0 {
+			return
+		}
+		if attempt >= u.maxAttempts {
+			fail(lost, fmt.Errorf("route changes to VPC %d were overwritten by a concurrent writer %d times", vpcID, attempt))
+			return
+		}
+		batch = lost
+
+		timer := time.NewTimer(u.retryDelay + rand.N(u.retryDelay))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			fail(batch, ctx.Err())
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func routeEntriesEqual(a, b []binarylane.RouteEntry) bool {
+	return slices.EqualFunc(a, b, func(x, y binarylane.RouteEntry) bool {
+		return x.Router == y.Router && x.Destination == y.Destination && ptrEqual(x.Description, y.Description)
+	})
+}
+
+func ptrEqual[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
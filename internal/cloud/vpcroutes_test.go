@@ -0,0 +1,169 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+// lockedClient makes the mock client safe for concurrent use, and widens the
+// window between reading and writing a VPC to expose lost updates.
+type lockedClient struct {
+	*mockClient
+	mu      sync.Mutex
+	updates atomic.Int32
+	// afterUpdate runs while the lock is held, e.g. to simulate another writer.
+	afterUpdate func(update int32, vpc *binarylane.Vpc)
+}
+
+func (c *lockedClient) GetVpc(ctx context.Context, vpcID int64) (*binarylane.Vpc, error) {
+	c.mu.Lock()
+	vpc, err := c.mockClient.GetVpc(ctx, vpcID)
+	if err == nil {
+		vpc = copyVpc(vpc)
+	}
+	c.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+	return vpc, err
+}
+
+func (c *lockedClient) PatchVpc(ctx context.Context, vpcID int64, req binarylane.PatchVpcRequest) (*binarylane.Vpc, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	update := c.updates.Add(1)
+	vpc, err := c.mockClient.PatchVpc(ctx, vpcID, req)
+	if err == nil && c.afterUpdate != nil {
+		c.afterUpdate(update, vpc)
+	}
+	return copyVpc(vpc), err
+}
+
+func newRouteTestClient(nodes int) *lockedClient {
+	mock := &mockClient{
+		servers: make(map[int64]*binarylane.Server),
+		vpcs: map[int64]*binarylane.Vpc{
+			10: {Id: 10, Name: "vpc", IpRange: "10.0.0.0/16"},
+		},
+	}
+	for i := 1; i <= nodes; i++ {
+		mock.servers[int64(i)] = &binarylane.Server{
+			Id:    int64(i),
+			Name:  fmt.Sprintf("node-%d", i),
+			VpcId: toPtr(int64(10)),
+			Networks: binarylane.Networks{
+				V4: []binarylane.Network{{IpAddress: fmt.Sprintf("10.0.0.%d", i), Type: "private"}},
+				// Already disabled, so that only the route table is written
+				SourceAndDestinationCheck: toPtr(false),
+			},
+		}
+	}
+	return &lockedClient{mockClient: mock}
+}
+
+func nodeRoute(i int) *cloudprovider.Route {
+	return &cloudprovider.Route{
+		TargetNode:      types.NodeName(fmt.Sprintf("node-%d", i)),
+		DestinationCIDR: fmt.Sprintf("10.244.%d.0/24", i),
+	}
+}
+
+func TestConcurrentRouteChanges(t *testing.T) {
+	const nodes = 20
+	client := newRouteTestClient(nodes)
+	r := &routes{client: client, cidr: "10.244.0.0/16", clusterID: "prod"}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= nodes; i++ {
+		wg.Go(func() {
+			if err := r.CreateRoute(ctx, "kubernetes", "", nodeRoute(i)); err != nil {
+				t.Errorf("CreateRoute(node-%d) error = %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := len(client.vpcs[10].RouteEntries); got != nodes {
+		t.Fatalf("RouteEntries = %d, want %d", got, nodes)
+	}
+	if got := client.vpcs[10].Name; got != "vpc" {
+		t.Errorf("Name = %q, want route changes to leave it unaltered", got)
+	}
+
+	// Delete the even routes while re-creating the odd ones
+	for i := 1; i <= nodes; i++ {
+		wg.Go(func() {
+			var err error
+			if i%2 == 0 {
+				err = r.DeleteRoute(ctx, "kubernetes", nodeRoute(i))
+			} else {
+				err = r.CreateRoute(ctx, "kubernetes", "", nodeRoute(i))
+			}
+			if err != nil {
+				t.Errorf("route change for node-%d error = %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	routes, err := r.ListRoutes(ctx, "kubernetes")
+	if err != nil {
+		t.Fatalf("ListRoutes() error = %v", err)
+	}
+	if len(routes) != nodes/2 {
+		t.Errorf("ListRoutes() = %d routes, want %d", len(routes), nodes/2)
+	}
+	for _, route := range routes {
+		var i int
+		if _, err := fmt.Sscanf(string(route.TargetNode), "node-%d", &i); err != nil || i%2 == 0 {
+			t.Errorf("unexpected route %s via %s", route.DestinationCIDR, route.TargetNode)
+		}
+	}
+}
+
+func TestRouteUpdateRetriesLostUpdate(t *testing.T) {
+	client := newRouteTestClient(1)
+	// Another writer replaces the table right after the first write
+	client.afterUpdate = func(update int32, vpc *binarylane.Vpc) {
+		if update == 1 {
+			vpc.RouteEntries = nil
+		}
+	}
+	r := &routes{client: client, cidr: "10.244.0.0/16"}
+	r.vpcUpdater().retryDelay = time.Millisecond
+
+	if err := r.CreateRoute(context.Background(), "kubernetes", "", nodeRoute(1)); err != nil {
+		t.Fatalf("CreateRoute() error = %v", err)
+	}
+	if got := client.updates.Load(); got != 2 {
+		t.Errorf("PatchVpc calls = %d, want 2", got)
+	}
+	if got := len(client.vpcs[10].RouteEntries); got != 1 {
+		t.Errorf("RouteEntries = %d, want 1", got)
+	}
+}
+
+func TestRouteUpdateGivesUpOnRepeatedLostUpdates(t *testing.T) {
+	client := newRouteTestClient(1)
+	client.afterUpdate = func(update int32, vpc *binarylane.Vpc) {
+		vpc.RouteEntries = nil
+	}
+	r := &routes{client: client, cidr: "10.244.0.0/16"}
+	r.vpcUpdater().retryDelay = time.Millisecond
+
+	if err := r.CreateRoute(context.Background(), "kubernetes", "", nodeRoute(1)); err == nil {
+		t.Fatal("CreateRoute() error = nil, want an error after repeated lost updates")
+	}
+	if got := client.updates.Load(); got != routeUpdateMaxAttempts {
+		t.Errorf("PatchVpc calls = %d, want %d", got, routeUpdateMaxAttempts)
+	}
+}
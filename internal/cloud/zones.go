@@ -0,0 +1,96 @@
+package cloud
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func (z *ZonesConfig) validate() error {
+	var errs []error
+
+	if z.Source != "" && !slices.Contains(zoneSources, z.Source) {
+		errs = append(errs, fmt.Errorf("source %q must be one of %q", z.Source, zoneSources))
+	}
+
+	if z.HostGroupPattern != "" {
+		if _, err := regexp.Compile(z.HostGroupPattern); err != nil {
+			errs = append(errs, fmt.Errorf("hostGroupPattern: %w", err))
+		}
+	}
+
+	if z.Source == ZoneSourceRegionMap && len(z.Regions) == 0 {
+		errs = append(errs, fmt.Errorf("regions must be set when source is %s", ZoneSourceRegionMap))
+	}
+	for region, zone := range z.Regions {
+		if msgs := validation.IsValidLabelValue(zone); zone == "" || len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("zone %q for region %s must be a valid label value", zone, region))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// zoner derives the topology zone of a server.
+type zoner struct {
+	source    ZoneSource
+	hostGroup *regexp.Regexp
+	regions   map[string]string
+}
+
+// newZoner builds a zoner from a validated config.
+func newZoner(cfg ZonesConfig) *zoner {
+	z := &zoner{
+		source:  cfg.Source,
+		regions: cfg.Regions,
+	}
+	if cfg.HostGroupPattern != "" {
+		z.hostGroup = regexp.MustCompile(cfg.HostGroupPattern)
+	}
+	return z
+}
+
+func (z *zoner) zone(server *binarylane.Server) string {
+	region := server.Region.Slug
+	if z == nil {
+		return region
+	}
+
+	switch z.source {
+	case ZoneSourceHost:
+		// Dedicated servers have no display name and are the only server on
+		// their host, so each is a zone of its own
+		if server.Host.DisplayName == "" {
+			return labelValue(fmt.Sprintf("%s-server-%d", region, server.Id))
+		}
+		return labelValue(strings.ToLower(region + "-" + z.hostGroupOf(server.Host.DisplayName)))
+	case ZoneSourceRegionMap:
+		if zone, ok := z.regions[region]; ok {
+			return zone
+		}
+	}
+	return region
+}
+
+// hostGroupOf returns the host group of a host display name. Hosts that do
+// not match the pattern are a group of their own, named by their display
+// name, so that they are not mixed with other hosts.
+func (z *zoner) hostGroupOf(displayName string) string {
+	if z.hostGroup == nil {
+		return displayName
+	}
+	match := z.hostGroup.FindStringSubmatch(displayName)
+	switch {
+	case match == nil:
+		return displayName
+	case len(match) > 1:
+		return match[1]
+	default:
+		return match[0]
+	}
+}
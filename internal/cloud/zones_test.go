@@ -0,0 +1,97 @@
+package cloud
+
+import (
+	"testing"
+
+	"github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/binarylane"
+)
+
+func TestZone(t *testing.T) {
+	server := func(region, host string) *binarylane.Server {
+		return &binarylane.Server{
+			Id:     42,
+			Region: binarylane.Region{Slug: region},
+			Host:   binarylane.Host{DisplayName: host},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		cfg    ZonesConfig
+		server *binarylane.Server
+		want   string
+	}{
+		{
+			name:   "defaults to region",
+			server: server("syd", "physical-host-01"),
+			want:   "syd",
+		},
+		{
+			name:   "host display name",
+			cfg:    ZonesConfig{Source: ZoneSourceHost},
+			server: server("syd", "Physical Host 01"),
+			want:   "syd-physical-host-01",
+		},
+		{
+			name:   "host group capture",
+			cfg:    ZonesConfig{Source: ZoneSourceHost, HostGroupPattern: `^(rack\d+)-`},
+			server: server("syd", "rack3-hv12"),
+			want:   "syd-rack3",
+		},
+		{
+			name:   "host group without a match",
+			cfg:    ZonesConfig{Source: ZoneSourceHost, HostGroupPattern: `^(rack\d+)-`},
+			server: server("syd", "hv12"),
+			want:   "syd-hv12",
+		},
+		{
+			name:   "dedicated host",
+			cfg:    ZonesConfig{Source: ZoneSourceHost},
+			server: server("per", ""),
+			want:   "per-server-42",
+		},
+		{
+			name:   "region map",
+			cfg:    ZonesConfig{Source: ZoneSourceRegionMap, Regions: map[string]string{"syd": "syd-1"}},
+			server: server("syd", "physical-host-01"),
+			want:   "syd-1",
+		},
+		{
+			name:   "region missing from map",
+			cfg:    ZonesConfig{Source: ZoneSourceRegionMap, Regions: map[string]string{"syd": "syd-1"}},
+			server: server("bne", ""),
+			want:   "bne",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.validate(); err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			if got := newZoner(tt.cfg).zone(tt.server); got != tt.want {
+				t.Errorf("zone() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZonesConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ZonesConfig
+	}{
+		{name: "unknown source", cfg: ZonesConfig{Source: "datacentre"}},
+		{name: "invalid pattern", cfg: ZonesConfig{Source: ZoneSourceHost, HostGroupPattern: "("}},
+		{name: "region map without regions", cfg: ZonesConfig{Source: ZoneSourceRegionMap}},
+		{name: "invalid zone", cfg: ZonesConfig{Source: ZoneSourceRegionMap, Regions: map[string]string{"syd": "syd 1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.validate(); err == nil {
+				t.Error("validate() expected error, got nil")
+			}
+		})
+	}
+}
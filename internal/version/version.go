@@ -0,0 +1,61 @@
+// Package version holds build information set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/oscarhermoso/binarylane-cloud-controller-manager/internal/version.version=v1.2.3"
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+const component = "binarylane-cloud-controller-manager"
+
+// Set with -ldflags -X at build time.
+var (
+	version   = "dev"
+	gitCommit = ""
+	buildDate = "unknown"
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the build information of the running binary. When the commit
+// was not set at link time, the VCS revision recorded by the Go toolchain is
+// used instead.
+func Get() Info {
+	commit := gitCommit
+	if commit == "" {
+		commit = "unknown"
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == "vcs.revision" {
+					commit = setting.Value
+				}
+			}
+		}
+	}
+
+	return Info{
+		Version:   version,
+		GitCommit: commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s %s (commit %s, built %s, %s %s)", component, i.Version, i.GitCommit, i.BuildDate, i.GoVersion, i.Platform)
+}
+
+// UserAgent returns the User-Agent sent with BinaryLane API requests.
+func UserAgent() string {
+	return fmt.Sprintf("%s/%s (%s)", component, version, runtime.GOOS+"/"+runtime.GOARCH)
+}
@@ -0,0 +1,22 @@
+package version
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	defer func(v, c, d string) { version, gitCommit, buildDate = v, c, d }(version, gitCommit, buildDate)
+	version, gitCommit, buildDate = "v1.2.3", "abc123", "2025-01-01T00:00:00Z"
+
+	info := Get()
+	if info.Version != "v1.2.3" || info.GitCommit != "abc123" || info.BuildDate != "2025-01-01T00:00:00Z" {
+		t.Errorf("Get() = %+v", info)
+	}
+	if !strings.HasPrefix(info.String(), "binarylane-cloud-controller-manager v1.2.3 (commit abc123") {
+		t.Errorf("String() = %q", info.String())
+	}
+	if !strings.HasPrefix(UserAgent(), "binarylane-cloud-controller-manager/v1.2.3 ") {
+		t.Errorf("UserAgent() = %q", UserAgent())
+	}
+}